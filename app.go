@@ -18,12 +18,17 @@ import (
 	"github.com/andreyvit/mvp/mvputil"
 	"github.com/andreyvit/mvp/postmark"
 	"github.com/uptrace/bunrouter"
+	"golang.org/x/time/rate"
 )
 
 type AppOptions struct {
 	Context       context.Context
 	Logf          func(format string, v ...interface{})
 	TestTransport http.RoundTripper
+
+	// Now overrides the clock used by App.Now and RC.Now, so that tests can
+	// control time-dependent behaviors like job retries and repeat intervals.
+	Now func() time.Time
 }
 
 type AppBehaviors struct {
@@ -47,6 +52,7 @@ type App struct {
 
 	stopApp func()
 	logf    func(format string, args ...any)
+	nowFunc func() time.Time
 
 	routesByName map[string]*Route
 	domainRouter *DomainRouter
@@ -63,7 +69,10 @@ type App struct {
 
 	methodsByName     map[string]*MethodImpl
 	jobsByKind        map[*mvpjobs.Kind]*JobImpl
+	jobSetLimiters    map[string]*rate.Limiter
+	runningJobs       runningJobs
 	ephemeralJobQueue EphemeralJobQueue
+	cacheValidators   cacheValidators
 	liveQueue         *mvplive.Queue
 
 	postmrk *postmark.Caller
@@ -104,6 +113,7 @@ func (app *App) Initialize(settings *Settings, opt AppOptions) {
 	app.Settings = settings
 	app.routesByName = make(map[string]*Route)
 	app.logf = opt.Logf
+	app.nowFunc = opt.Now
 	app.stopApp = stopApp
 	app.defaultHTTPClient.Timeout = 30 * time.Second
 	app.defaultHTTPClient.Transport = opt.TestTransport
@@ -123,9 +133,14 @@ func (app *App) Initialize(settings *Settings, opt AppOptions) {
 	for _, mod := range app.Settings.Configuration.Modules {
 		app.addModule(mod)
 	}
+	if len(app.JobSchema.PersistentKindNames()) > 0 {
+		app.JobSchema.Include(builtinJobSchema)
+	}
 	for _, kind := range app.JobSchema.Kinds() {
 		if kind.IsPersistent() {
 			app.JobImpl(kind)
+		} else {
+			app.checkEphemeralSpillKind(kind)
 		}
 	}
 	log.Printf("app jobs: %v", app.JobSchema.PersistentKindNames())
@@ -168,6 +183,7 @@ func (app *App) addModule(mod *Module) {
 
 func (app *App) Close() {
 	app.stopApp()
+	app.ephemeralJobQueue.spills.Wait()
 	runHooksRev1(app.Hooks.closeApp, app)
 	closeAppDB(app)
 }
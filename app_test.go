@@ -0,0 +1,114 @@
+package mvp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/mvp"
+	"github.com/andreyvit/mvp/mvpjobs"
+	"github.com/andreyvit/mvp/mvpjobstest"
+)
+
+var testStartTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const testBaseURL = "http://example.com"
+
+type testJobParams struct {
+	Name  string `json:"-"`
+	Value int    `json:"value,omitempty"`
+}
+
+func (p *testJobParams) JobName() string             { return p.Name }
+func (p *testJobParams) SetJobName(name string)      { p.Name = name }
+func (p *testJobParams) JobAccountID() mvpjobs.JobID { return 0 }
+
+// newTestApp returns an initialized app with an empty views directory,
+// the jobs defined by define, and a harness driving its fake clock.
+func newTestApp(t testing.TB, define func(scm *mvpjobs.Schema), opts ...func(settings *mvp.Settings)) *mvpjobstest.Harness {
+	root := t.TempDir()
+	for _, dir := range []string{"views", "static", "data"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var scm mvpjobs.Schema
+	if define != nil {
+		define(&scm)
+	}
+	settings := &mvp.Settings{
+		Env:     "test",
+		AppID:   "mvptest",
+		DataDir: filepath.Join(root, "data"),
+		Configuration: &mvp.Configuration{
+			ConfigFileName:  "test.json",
+			LocalDevAppRoot: root,
+			ViewsSubdir:     "views",
+			StaticSubdir:    "static",
+			Modules: []*mvp.Module{
+				{Name: "test", JobSchema: &scm},
+			},
+		},
+	}
+	settings.ServeAssetsFromDisk = true
+	for _, f := range opts {
+		f(settings)
+	}
+
+	clock := mvpjobstest.NewClock(testStartTime)
+	app := new(mvp.App)
+	for _, mod := range settings.Configuration.Modules {
+		if mod.SetupHooks != nil {
+			mod.SetupHooks(app)
+		}
+	}
+	app.Initialize(settings, mvp.AppOptions{Now: clock.Now})
+	t.Cleanup(app.Close)
+	return mvpjobstest.New(t, app, clock)
+}
+
+// withRoutes adds routes to DefaultSite, served at testBaseURL.
+func withRoutes(f func(b *mvp.RouteBuilder)) func(settings *mvp.Settings) {
+	return func(settings *mvp.Settings) {
+		settings.BaseURL = testBaseURL
+		settings.Configuration.Modules = append(settings.Configuration.Modules, &mvp.Module{
+			Name: "testroutes",
+			SetupHooks: func(app *mvp.App) {
+				app.Hooks.SiteRoutes(mvp.DefaultSite, f)
+			},
+		})
+	}
+}
+
+// withViews writes the given views, keyed by path relative to the views
+// directory.
+func withViews(t testing.TB, files map[string]string) func(settings *mvp.Settings) {
+	return func(settings *mvp.Settings) {
+		for name, code := range files {
+			path := filepath.Join(settings.Configuration.LocalDevAppRoot, settings.Configuration.ViewsSubdir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(code), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func serve(h *mvpjobstest.Harness, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.App.ServeHTTP(w, r)
+	return w
+}
+
+func mustWrite(h *mvpjobstest.Harness, f func(rc *mvp.RC)) {
+	rc := mvp.NewRC(context.Background(), h.App, "test")
+	defer rc.Close()
+	rc.MustWrite(func() { f(rc) })
+}
@@ -13,33 +13,66 @@ var (
 		Name: "mvpbuiltin",
 	}
 
+	// builtinJobSchema is only included by apps that have persistent jobs.
+	builtinJobSchema = &mvpjobs.Schema{
+		Name: "mvpbuiltin",
+	}
+
 	builtinModule = &Module{
 		Name:     "mvpbuiltin",
 		DBSchema: builtinDBSchema,
 	}
 
+	pruneJobsKind = builtinJobSchema.Define("PruneJobs", pruneJobs, mvpjobs.Cron, mvpjobs.WithSchedule("@hourly"))
+
 	jobsTable = edb.AddTable(builtinDBSchema, "jobs", 2, func(row *mvpjobs.Job, ib *edb.IndexBuilder) {
 		ib.Add(jobsByKind, row.Kind)
 		if !row.IsAnonymous() {
 			ib.Add(jobsByKindName, row.KindName())
 		}
 		if !row.NextRunTime.IsZero() && row.Status.IsPending() {
+			ib.Add(pendingJobsByQueueKey, row.QueueKey())
 			ib.Add(pendingJobsByRunTime, row.NextRunTime)
 		}
 		if row.Status.IsRunning() {
 			ib.Add(runningJobsByStartTime, row.StartTime)
 		}
+		if !row.Status.IsTerminal() {
+			ib.Add(activeJobsByKindStatus, row.KindStatus())
+			if row.Partition != "" {
+				ib.Add(activeJobsByPartition, row.PartitionSlot())
+			}
+		}
+		if row.Status.IsTerminal() && row.IsAnonymous() {
+			ib.Add(finishedJobsByKindStatusTime, row.FinishKey())
+		}
+		if row.Status == mvpjobs.StatusBlocked {
+			for _, dep := range row.Deps {
+				ib.Add(blockedJobsByDep, dep)
+			}
+		}
 	}, func(tx *edb.Tx, row *mvpjobs.Job, oldVer uint64) {
 	}, []*edb.Index{
 		jobsByKind,
 		jobsByKindName,
+		pendingJobsByQueueKey,
 		pendingJobsByRunTime,
 		runningJobsByStartTime,
+		blockedJobsByDep,
+		finishedJobsByKindStatusTime,
+		activeJobsByKindStatus,
+		activeJobsByPartition,
 	})
 	jobsByKind             = edb.AddIndex[string]("by_kind")
 	jobsByKindName         = edb.AddIndex[mvpjobs.KindName]("by_kind_name_v2").Unique()
-	pendingJobsByRunTime   = edb.AddIndex[time.Time]("pending_by_run_time")
+	pendingJobsByQueueKey  = edb.AddIndex[mvpjobs.QueueKey]("pending_by_kind_prio_run_time")
+	pendingJobsByRunTime   = edb.AddIndex[time.Time]("pending_by_run_time_v2")
 	runningJobsByStartTime = edb.AddIndex[time.Time]("running_by_start_time")
+	blockedJobsByDep       = edb.AddIndex[mvpjobs.KindName]("blocked_by_dep")
+
+	finishedJobsByKindStatusTime = edb.AddIndex[mvpjobs.FinishKey]("finished_anon_by_kind_status_time")
+	activeJobsByKindStatus       = edb.AddIndex[mvpjobs.KindStatus]("active_by_kind_status")
+	activeJobsByPartition        = edb.AddIndex[mvpjobs.PartitionSlot]("active_by_partition")
 
 	migrationsTable = edb.AddTable(builtinDBSchema, "migrations", 1, func(row *mvpm.MigrationRecord, ib *edb.IndexBuilder) {
 	}, nil, []*edb.Index{})
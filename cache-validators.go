@@ -0,0 +1,175 @@
+package mvp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andreyvit/mvp/fnv"
+	"github.com/andreyvit/mvp/mvphttp"
+	"github.com/andreyvit/mvp/mvputil"
+)
+
+// cacheValidatorBuckets is the number of buckets cache validator keys are
+// hashed into. Keys sharing a bucket are busted together, which only costs
+// an extra refetch, but keeps memory use fixed.
+const cacheValidatorBuckets = 1024
+
+// cacheValidators tracks BustCache calls for the keys used by ETagFromKeys
+// and SetLastModified.
+type cacheValidators struct {
+	mut      sync.Mutex
+	epoch    time.Time                        // validators from before a restart never match
+	seq      uint64                           // number of busts so far
+	versions [cacheValidatorBuckets]uint64    // seq of the last bust
+	bustTime [cacheValidatorBuckets]time.Time // time of the last bust
+}
+
+// ETagFromKeys makes the response of a GET or HEAD request carry a weak ETag
+// built from the given keys, so that a matching If-None-Match gets
+// 304 Not Modified. The ETag changes whenever any of the keys is passed to
+// BustCache, and whenever the app restarts.
+//
+// No ETag is set if any of the keys has been busted since the request has
+// started reading the database, because the data read might predate
+// the change.
+func (rc *RC) ETagFromKeys(keys ...any) {
+	h := fnv.New128()
+	v := &rc.app.cacheValidators
+	v.mut.Lock()
+	defer v.mut.Unlock()
+	v.initLocked(rc.app.Now())
+	h.WriteUint64(uint64(v.epoch.UnixNano()))
+	for _, key := range keys {
+		b := cacheValidatorBucket(key)
+		if rc.bustedSinceTxStartLocked(b) {
+			rc.etag = ""
+			return
+		}
+		h.WriteStringZ(cacheValidatorKeyString(key))
+		h.WriteUint64(v.versions[b])
+	}
+	rc.etag = `W/"` + h.String() + `"`
+}
+
+// SetLastModified makes the response of a GET or HEAD request carry
+// a Last-Modified header, so that a later If-Modified-Since gets
+// 304 Not Modified. When keys are given, the time is bumped to the last
+// BustCache call for any of them, or to the app start; like with
+// ETagFromKeys, no header is set if any of them has been busted since
+// the request has started reading the database.
+func (rc *RC) SetLastModified(t time.Time, keys ...any) {
+	if len(keys) > 0 {
+		v := &rc.app.cacheValidators
+		v.mut.Lock()
+		defer v.mut.Unlock()
+		v.initLocked(rc.app.Now())
+		if t.Before(v.epoch) {
+			t = v.epoch
+		}
+		for _, key := range keys {
+			b := cacheValidatorBucket(key)
+			if rc.bustedSinceTxStartLocked(b) {
+				rc.lastModified = time.Time{}
+				return
+			}
+			if bt := v.bustTime[b]; t.Before(bt) {
+				t = bt
+			}
+		}
+	}
+	rc.lastModified = t.UTC().Truncate(time.Second)
+}
+
+// noteTxStart remembers how many busts have happened before the request
+// started reading the database, see bustedSinceTxStartLocked.
+func (rc *RC) noteTxStart() {
+	if rc.cacheValidatorsSeq != 0 {
+		return
+	}
+	v := &rc.app.cacheValidators
+	v.mut.Lock()
+	defer v.mut.Unlock()
+	rc.cacheValidatorsSeq = v.seq + 1 // 0 means no transaction yet
+}
+
+func (rc *RC) bustedSinceTxStartLocked(bucket int) bool {
+	return rc.cacheValidatorsSeq != 0 && rc.app.cacheValidators.versions[bucket] >= rc.cacheValidatorsSeq
+}
+
+func (v *cacheValidators) initLocked(now time.Time) {
+	if v.epoch.IsZero() {
+		v.epoch = now.Truncate(time.Second)
+	}
+}
+
+// bust invalidates the validators built from the given key (and any other
+// keys sharing its bucket).
+func (v *cacheValidators) bust(key any, now time.Time) {
+	b := cacheValidatorBucket(key)
+	v.mut.Lock()
+	defer v.mut.Unlock()
+	v.seq++
+	v.versions[b] = v.seq
+	v.bustTime[b] = now.Truncate(time.Second).Add(time.Second) // Last-Modified has 1s precision
+}
+
+func cacheValidatorKeyString(key any) string {
+	return fmt.Sprintf("%T:%v", key, key)
+}
+
+func cacheValidatorBucket(key any) int {
+	return int(fnv.String128(cacheValidatorKeyString(key)).Downmix64() % cacheValidatorBuckets)
+}
+
+// applyCacheValidators adds the validators declared by the handler to the
+// response, and returns whether the request's conditions say that the client
+// has a fresh copy, in which case 304 Not Modified should be sent.
+func (rc *RC) applyCacheValidators(w http.ResponseWriter, r *http.Request) bool {
+	if rc.etag == "" && rc.lastModified.IsZero() {
+		return false
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	etag := rc.etag
+	if etag != "" && rc.Route != nil && rc.Route.htmlOrJSON && mvputil.PreferJSON(r) {
+		etag = strings.TrimSuffix(etag, `"`) + `-json"` // HTML and JSON variants differ
+	}
+
+	h := w.Header()
+	if etag != "" {
+		h.Set("ETag", etag)
+	}
+	if !rc.lastModified.IsZero() {
+		h.Set("Last-Modified", rc.lastModified.Format(http.TimeFormat))
+	}
+	if h.Get("Cache-Control") == "" {
+		mvphttp.ApplyCacheMode(w, mvphttp.PrivateMutable)
+	}
+
+	var notModified bool
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		notModified = etag != "" && etagListMatches(inm, etag)
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" && !rc.lastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		notModified = (err == nil && !rc.lastModified.After(t))
+	}
+	return notModified
+}
+
+// etagListMatches performs the weak comparison of an If-None-Match value
+// against an ETag.
+func etagListMatches(list, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" || strings.TrimPrefix(item, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
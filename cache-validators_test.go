@@ -0,0 +1,149 @@
+package mvp_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/mvp"
+	"github.com/andreyvit/mvp/mvpjobstest"
+)
+
+type testItemKey struct {
+	ID string
+}
+
+type testItemIn struct {
+	ID string `json:"-" form:"id,path"`
+}
+
+// newCacheTestApp serves GET /items/:id with validators built from
+// testItemKey, calling during (if set) before declaring them.
+func newCacheTestApp(t *testing.T, during *func(rc *mvp.RC)) *mvpjobstest.Harness {
+	return newTestApp(t, nil, withRoutes(func(b *mvp.RouteBuilder) {
+		b.Route("items.get", "GET /items/:id", func(rc *mvp.RC, in *testItemIn) (*testUser, error) {
+			if *during != nil {
+				(*during)(rc)
+			}
+			rc.ETagFromKeys(testItemKey{in.ID})
+			rc.SetLastModified(testStartTime, testItemKey{in.ID})
+			return &testUser{ID: in.ID}, nil
+		})
+	}), func(settings *mvp.Settings) {
+		settings.Configuration.Modules = append(settings.Configuration.Modules, &mvp.Module{
+			Name: "testcache",
+			SetupHooks: func(app *mvp.App) {
+				app.Hooks.CacheValidatorKeys(testItemKey{})
+			},
+		})
+	})
+}
+
+func bustCache(h *mvpjobstest.Harness, keys ...any) {
+	rc := mvp.NewRC(context.Background(), h.App, "test")
+	defer rc.Close()
+	rc.BustCache(keys...)
+}
+
+func getItem(h *mvpjobstest.Harness, id string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", testBaseURL+"/items/"+id, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	return serve(h, r)
+}
+
+func TestETagFromKeys(t *testing.T) {
+	var during func(rc *mvp.RC)
+	h := newCacheTestApp(t, &during)
+
+	w := getItem(h, "a")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("** GET = %d, ETag %q", w.Code, etag)
+	}
+	if w := getItem(h, "a", "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Errorf("** GET with matching If-None-Match = %d, wanted 304", w.Code)
+	}
+	if w := getItem(h, "a", "If-None-Match", `"other", `+etag); w.Code != http.StatusNotModified {
+		t.Errorf("** GET with matching If-None-Match list = %d, wanted 304", w.Code)
+	}
+	if w := getItem(h, "b", "If-None-Match", etag); w.Code != http.StatusOK {
+		t.Errorf("** GET of another item = %d, wanted 200", w.Code)
+	}
+
+	bustCache(h, testItemKey{"a"})
+	w = getItem(h, "a", "If-None-Match", etag)
+	if w.Code != http.StatusOK {
+		t.Errorf("** GET after BustCache = %d, wanted 200", w.Code)
+	}
+	if a := w.Header().Get("ETag"); a == etag || a == "" {
+		t.Errorf("** ETag after BustCache = %q, wanted a new one", a)
+	}
+}
+
+func TestSetLastModified(t *testing.T) {
+	var during func(rc *mvp.RC)
+	h := newCacheTestApp(t, &during)
+
+	w := getItem(h, "a")
+	lm := w.Header().Get("Last-Modified")
+	if e := testStartTime.Format(http.TimeFormat); w.Code != http.StatusOK || lm != e {
+		t.Fatalf("** GET = %d, Last-Modified %q, wanted %q", w.Code, lm, e)
+	}
+	if w := getItem(h, "a", "If-Modified-Since", lm); w.Code != http.StatusNotModified {
+		t.Errorf("** GET with If-Modified-Since = %d, wanted 304", w.Code)
+	}
+	// If-None-Match takes precedence
+	if w := getItem(h, "a", "If-Modified-Since", lm, "If-None-Match", `"other"`); w.Code != http.StatusOK {
+		t.Errorf("** GET with mismatching If-None-Match = %d, wanted 200", w.Code)
+	}
+
+	h.Clock.Advance(time.Hour)
+	bustCache(h, testItemKey{"a"})
+	w = getItem(h, "a", "If-Modified-Since", lm)
+	if w.Code != http.StatusOK {
+		t.Errorf("** GET after BustCache = %d, wanted 200", w.Code)
+	}
+	// the bust time comes from the app clock, rounded up to a second
+	if a, e := w.Header().Get("Last-Modified"), testStartTime.Add(time.Hour+time.Second).Format(http.TimeFormat); a != e {
+		t.Errorf("** Last-Modified after BustCache = %q, wanted %q", a, e)
+	}
+}
+
+func TestCacheValidators_bustedDuringRequest(t *testing.T) {
+	var during func(rc *mvp.RC)
+	h := newCacheTestApp(t, &during)
+
+	// a write committed after the request has started reading
+	during = func(rc *mvp.RC) { bustCache(h, testItemKey{"a"}) }
+	w := getItem(h, "a")
+	if w.Code != http.StatusOK {
+		t.Fatalf("** GET = %d", w.Code)
+	}
+	if a := w.Header().Get("ETag"); a != "" {
+		t.Errorf("** ETag = %q, wanted none since the data read may be stale", a)
+	}
+	if a := w.Header().Get("Last-Modified"); a != "" {
+		t.Errorf("** Last-Modified = %q, wanted none since the data read may be stale", a)
+	}
+
+	during = nil
+	if w := getItem(h, "a"); w.Header().Get("ETag") == "" {
+		t.Errorf("** no ETag on the next request")
+	}
+}
+
+func TestBustCache_unknownKey(t *testing.T) {
+	var during func(rc *mvp.RC)
+	h := newCacheTestApp(t, &during)
+	defer func() {
+		if a, e := fmt.Sprint(recover()), "don't know how to bust cache for key string unknown"; a != e {
+			t.Errorf("** panic = %q, wanted %q", a, e)
+		}
+	}()
+	bustCache(h, "unknown")
+}
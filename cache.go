@@ -11,6 +11,10 @@ type CacheBustingSession struct {
 	Busted []bool
 }
 
+// BustCache invalidates the caches handled by BustCache hooks and the cache
+// validators built from the given keys (see ETagFromKeys). Within a write
+// transaction, this happens once the transaction ends. Panics for keys that
+// are neither handled by a hook nor declared via Hooks.CacheValidatorKeys.
 func (rc *RC) BustCache(keys ...any) {
 	if rc.IsInWriteTx() {
 		if rc.cacheBusting == nil {
@@ -37,7 +41,9 @@ func bustCaches(rc *RC, keys []any) {
 	if len(keys) == 0 {
 		return
 	}
+	now := rc.app.Now() // not rc.Now(), which may be the start of the request
 	for _, key := range keys {
+		rc.app.cacheValidators.bust(key, now)
 		if !runHooksFwd2Or(rc.app.Hooks.bustCache, rc, key) {
 			panic(fmt.Errorf("don't know how to bust cache for key %T %v", key, key))
 		}
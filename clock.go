@@ -11,6 +11,10 @@ var (
 	RealStartTime = Value[time.Time]("real_start_time")
 )
 
+// Now returns the current time, as reported by AppOptions.Now if provided.
 func (app *App) Now() time.Time {
+	if app.nowFunc != nil {
+		return app.nowFunc()
+	}
 	return time.Now()
 }
@@ -0,0 +1,57 @@
+package mvp
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+const (
+	csrfCookieName = "csrf"
+	csrfFieldName  = "_csrf"
+	csrfHeaderName = "X-CSRF-Token"
+	csrfTokenLen   = 32
+)
+
+// CSRFToken returns the CSRF token of the current browser, issuing a new one
+// (via a cookie) if needed. Forms posting to CSRFProtected routes must include
+// it as a _csrf field; API clients can send it in X-CSRF-Token header instead.
+//
+// Call it from the handler rather than while rendering, because the cookie
+// has to be set before the response is written.
+func (rc *RC) CSRFToken() string {
+	if rc.csrfToken != "" {
+		return rc.csrfToken
+	}
+	if c, err := rc.Request.Request.Cookie(csrfCookieName); err == nil && len(c.Value) == csrfTokenLen {
+		rc.csrfToken = c.Value
+		return rc.csrfToken
+	}
+	rc.csrfToken = RandomHex(csrfTokenLen)
+	rc.SetCookie(&http.Cookie{
+		Name:     csrfCookieName,
+		Value:    rc.csrfToken,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   !rc.app.Settings.AllowInsecureHttp,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return rc.csrfToken
+}
+
+// checkCSRF verifies that the request carries the token from the CSRF cookie,
+// which a cross-site form cannot do. Must be called after the form is parsed.
+func (rc *RC) checkCSRF() error {
+	r := rc.Request.Request
+	c, err := r.Cookie(csrfCookieName)
+	if err != nil || c.Value == "" {
+		return ErrCSRF.Msg("missing CSRF cookie")
+	}
+	token := r.Form.Get(csrfFieldName)
+	if token == "" {
+		token = r.Header.Get(csrfHeaderName)
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(c.Value)) != 1 {
+		return ErrCSRF.Msg("invalid CSRF token")
+	}
+	return nil
+}
@@ -45,6 +45,7 @@ func (app *App) NewID() flake.ID {
 
 func (rc *RC) DBTx() *edb.Tx {
 	if rc.tx == nil {
+		rc.noteTxStart()
 		rc.tx = rc.app.db.BeginRead()
 	}
 	return rc.tx
@@ -62,6 +63,7 @@ func (rc *RC) InTx(affinity mvpm.StoreAffinity, f func() error) error {
 		}
 	}
 	isWrite := affinity.IsWriter()
+	rc.noteTxStart()
 	err := rc.app.db.Tx(isWrite, func(tx *edb.Tx) error {
 		rc.tx = tx
 		tx.OnChange(rc.app.dbMonitoringOptions, rc.onDBChange)
@@ -71,7 +73,7 @@ func (rc *RC) InTx(affinity mvpm.StoreAffinity, f func() error) error {
 		return f()
 	})
 	if isWrite {
-		rc.handleWriteTxEnded()
+		rc.handleWriteTxEnded(err)
 	}
 	return err
 }
@@ -80,8 +82,9 @@ func (rc *RC) IsInWriteTx() bool {
 	return rc.tx != nil && rc.tx.IsWritable()
 }
 
-func (rc *RC) handleWriteTxEnded() {
+func (rc *RC) handleWriteTxEnded(err error) {
 	rc.applyDelayedCacheBusting()
+	rc.applyDelayedJobCancellation(err == nil)
 }
 
 // func (rc *RC) Commit() error {
@@ -11,11 +11,14 @@ var (
 	ErrTooManyRequests = httperrors.Define(http.StatusTooManyRequests, "too_many_requests")
 	ErrInvalidToken    = httperrors.Define(http.StatusUnauthorized, "invalid_token")
 	ErrForbidden       = httperrors.Define(http.StatusForbidden, "forbidden")
+	ErrCSRF            = httperrors.Define(http.StatusForbidden, "csrf")
 
 	ErrAPIInvalidMethod          = httperrors.Define(http.StatusMethodNotAllowed, "invalid_http_method")
 	ErrAPIUnsupportedContentType = httperrors.Define(http.StatusUnsupportedMediaType, "invalid_content_type")
 	ErrAPIInvalidJSON            = httperrors.Define(http.StatusBadRequest, "invalid_json")
 	ErrAPIUnknownMethod          = httperrors.Define(http.StatusNotFound, "unknown_method")
+	ErrAPIRedirected             = httperrors.Define(http.StatusForbidden, "redirected")
+	ErrAPINotAcceptable          = httperrors.Define(http.StatusNotAcceptable, "not_acceptable")
 
 	ErrPanic = httperrors.Define(http.StatusInternalServerError, "internal_server_error")
 )
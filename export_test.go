@@ -0,0 +1,59 @@
+package mvp
+
+import (
+	"context"
+	"time"
+
+	"github.com/andreyvit/mvp/mvpjobs"
+)
+
+// SetJobHeartbeatInterval lets external tests exercise heartbeats without
+// waiting for a minute.
+func SetJobHeartbeatInterval(d time.Duration) (restore func()) {
+	old := jobHeartbeatInterval
+	jobHeartbeatInterval = d
+	return func() { jobHeartbeatInterval = old }
+}
+
+// SetJobEnqueueChunkSize lets external tests see EnqueueBatch commit chunks
+// without enqueueing thousands of jobs.
+func SetJobEnqueueChunkSize(n int) (restore func()) {
+	old := jobEnqueueChunkSize
+	jobEnqueueChunkSize = n
+	return func() { jobEnqueueChunkSize = old }
+}
+
+// SweepStuckJobs runs a single pass of the stuck job sweeper.
+func (app *App) SweepStuckJobs(ctx context.Context) int {
+	rc := NewRC(ctx, app, "jobs:test")
+	defer rc.Close()
+	return app.sweepStuckJobs(rc)
+}
+
+// RunJobsAsWorker runs due jobs like an idle worker of the default set would,
+// returning the number of jobs executed and how long the worker would sleep.
+func (app *App) RunJobsAsWorker(ctx context.Context) (int, time.Duration) {
+	rc := NewRC(ctx, app, "jobs:test")
+	defer rc.Close()
+	count, retryAt := app.runPendingJobsOnce(rc, "", 1, 1)
+	return count, app.jobWorkerIdleDelay(rc, retryAt)
+}
+
+var RunCommand = runCommand
+
+// SeedCronJobs seeds cron jobs like StartJobWorkers does on every startup.
+func (app *App) SeedCronJobs(ctx context.Context) {
+	app.seedCronJobs(ctx)
+}
+
+// JobImplOf lets tests change the definition of a job kind, the way
+// a redeploy would.
+func (app *App) JobImplOf(kind *mvpjobs.Kind) *JobImpl {
+	return app.jobsByKind[kind]
+}
+
+// WaitForEphemeralSpills waits for the ephemeral jobs spilled on overflow
+// to be enqueued.
+func (app *App) WaitForEphemeralSpills() {
+	app.ephemeralJobQueue.spills.Wait()
+}
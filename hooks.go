@@ -2,8 +2,11 @@ package mvp
 
 import (
 	"html/template"
+	"reflect"
+	"slices"
 
 	"github.com/andreyvit/edb"
+	"github.com/andreyvit/mvp/mvpjobs"
 )
 
 type Hooks struct {
@@ -25,6 +28,11 @@ type Hooks struct {
 	urlGenOption []func(app *App, g *URLGen, option string) bool
 	urlGen       []func(app *App, g *URLGen)
 	jwtTokenKey  []func(rc *RC, c *TokenDecoding) error
+	archiveJob   []func(rc *RC, j *mvpjobs.Job)
+	jobStarted   []func(rc *RC, j *mvpjobs.Job)
+	jobSucceeded []func(rc *RC, j *mvpjobs.Job)
+	jobRetrying  []func(rc *RC, j *mvpjobs.Job, err error)
+	jobFailed    []func(rc *RC, j *mvpjobs.Job, err error)
 }
 
 func (h *Hooks) InitApp(f func(app *App, init *AppInit)) {
@@ -90,6 +98,19 @@ func (h *Hooks) BustCache(f func(rc *RC, key any) bool) {
 	h.bustCache = append(h.bustCache, f)
 }
 
+// CacheValidatorKeys declares that keys of the same types as the given
+// samples only back cache validators (see ETagFromKeys), so that BustCache
+// accepts them without a cache of their own.
+func (h *Hooks) CacheValidatorKeys(samples ...any) {
+	types := make([]reflect.Type, len(samples))
+	for i, sample := range samples {
+		types[i] = reflect.TypeOf(sample)
+	}
+	h.BustCache(func(rc *RC, key any) bool {
+		return slices.Contains(types, reflect.TypeOf(key))
+	})
+}
+
 func (h *Hooks) URLGen(f func(app *App, g *URLGen)) {
 	h.urlGen = append(h.urlGen, f)
 }
@@ -98,6 +119,36 @@ func (h *Hooks) JWTTokenKey(f func(rc *RC, c *TokenDecoding) error) {
 	h.jwtTokenKey = append(h.jwtTokenKey, f)
 }
 
+// ArchiveJob is called within a write transaction for every finished job
+// that is about to be deleted because it has outlived its kind's retention.
+func (h *Hooks) ArchiveJob(f func(rc *RC, j *mvpjobs.Job)) {
+	h.archiveJob = append(h.archiveJob, f)
+}
+
+// JobStarted is called when a persistent job is dequeued, within the write
+// transaction that marks it as running.
+func (h *Hooks) JobStarted(f func(rc *RC, j *mvpjobs.Job)) {
+	h.jobStarted = append(h.jobStarted, f)
+}
+
+// JobSucceeded is called within the write transaction that records a successful
+// run of a persistent job.
+func (h *Hooks) JobSucceeded(f func(rc *RC, j *mvpjobs.Job)) {
+	h.jobSucceeded = append(h.jobSucceeded, f)
+}
+
+// JobRetrying is called within the write transaction that records a failed run
+// of a persistent job which will be retried according to its backoff.
+func (h *Hooks) JobRetrying(f func(rc *RC, j *mvpjobs.Job, err error)) {
+	h.jobRetrying = append(h.jobRetrying, f)
+}
+
+// JobFailed is called within the write transaction that marks a persistent
+// job as permanently failed after exhausting its backoff.
+func (h *Hooks) JobFailed(f func(rc *RC, j *mvpjobs.Job, err error)) {
+	h.jobFailed = append(h.jobFailed, f)
+}
+
 func (h *Hooks) DBChange(f func(rc *RC, chg *edb.Change)) {
 	h.dbChange = append(h.dbChange, f)
 }
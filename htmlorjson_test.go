@@ -0,0 +1,82 @@
+package mvp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andreyvit/mvp"
+	"github.com/andreyvit/mvp/mvpjobstest"
+)
+
+func newHTMLOrJSONTestApp(t *testing.T) *mvpjobstest.Harness {
+	show := func(rc *mvp.RC, in *testItemIn) (*mvp.ViewData, error) {
+		rc.ETagFromKeys(testItemKey{in.ID})
+		return &mvp.ViewData{View: "item", Data: &testUser{ID: in.ID}}, nil
+	}
+	return newTestApp(t, nil, withRoutes(func(b *mvp.RouteBuilder) {
+		b.Route("items.show", "GET /items/:id", show, mvp.HTMLOrJSON)
+		b.Group("/private", func(b *mvp.RouteBuilder) {
+			b.Use(func(rc *mvp.RC) (*mvp.Redirect, error) {
+				return &mvp.Redirect{Path: "/login"}, nil
+			})
+			b.Route("private.show", "GET /items/:id", show, mvp.HTMLOrJSON)
+		})
+	}), withViews(t, map[string]string{
+		"layouts/default.html": `{{.Content}}`,
+		"item.html":            `<p>Item {{.ID}}</p>`,
+	}))
+}
+
+func get(h *mvpjobstest.Harness, path, accept string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", testBaseURL+path, nil)
+	r.Header.Set("Accept", accept)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	return serve(h, r)
+}
+
+func TestHTMLOrJSON(t *testing.T) {
+	h := newHTMLOrJSONTestApp(t)
+
+	w := get(h, "/items/a", "text/html")
+	if a, e := strings.TrimSpace(w.Body.String()), "<p>Item a</p>"; w.Code != http.StatusOK || a != e {
+		t.Errorf("** HTML = %d %s, wanted 200 %s", w.Code, a, e)
+	}
+	htmlETag := w.Header().Get("ETag")
+
+	w = get(h, "/items/a", "application/json")
+	if a, e := strings.TrimSpace(w.Body.String()), `{"id":"a","manager":null}`; w.Code != http.StatusOK || a != e {
+		t.Errorf("** JSON = %d %s, wanted 200 %s", w.Code, a, e)
+	}
+	jsonETag := w.Header().Get("ETag")
+	if htmlETag == "" || jsonETag == "" || htmlETag == jsonETag {
+		t.Fatalf("** ETags = %q and %q, wanted distinct ones", htmlETag, jsonETag)
+	}
+
+	if w := get(h, "/items/a", "application/json", "If-None-Match", htmlETag); w.Code != http.StatusOK {
+		t.Errorf("** JSON with the HTML ETag = %d, wanted 200", w.Code)
+	}
+	if w := get(h, "/items/a", "text/html", "If-None-Match", jsonETag); w.Code != http.StatusOK {
+		t.Errorf("** HTML with the JSON ETag = %d, wanted 200", w.Code)
+	}
+	if w := get(h, "/items/a", "application/json", "If-None-Match", jsonETag); w.Code != http.StatusNotModified {
+		t.Errorf("** JSON with the JSON ETag = %d, wanted 304", w.Code)
+	}
+}
+
+func TestHTMLOrJSON_middlewareRedirect(t *testing.T) {
+	h := newHTMLOrJSONTestApp(t)
+
+	w := get(h, "/private/items/a", "text/html")
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login" {
+		t.Errorf("** HTML = %d to %q, wanted 303 to /login", w.Code, w.Header().Get("Location"))
+	}
+
+	w = get(h, "/private/items/a", "application/json")
+	if a, e := strings.TrimSpace(w.Body.String()), `{"error":"redirected","message":"/login"}`; w.Code != http.StatusForbidden || a != e {
+		t.Errorf("** JSON = %d %s, wanted 403 %s", w.Code, a, e)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 type Error struct {
@@ -97,8 +98,25 @@ func Errorf(statusCode int, id string, msg string, args ...any) Interface {
 	}
 }
 
+var (
+	definedMut sync.Mutex
+	defined    []BaseError
+)
+
 func Define(statusCode int, id string) BaseError {
-	return BaseError{id, statusCode}
+	base := BaseError{id, statusCode}
+	definedMut.Lock()
+	defined = append(defined, base)
+	definedMut.Unlock()
+	return base
+}
+
+// Defined returns all errors created via Define so far, in order, which is
+// handy for documenting APIs.
+func Defined() []BaseError {
+	definedMut.Lock()
+	defer definedMut.Unlock()
+	return append([]BaseError(nil), defined...)
 }
 
 func (base BaseError) Error() string {
@@ -0,0 +1,147 @@
+package mvp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/andreyvit/edb"
+	"github.com/andreyvit/mvp/flogger"
+	"github.com/andreyvit/mvp/mvpjobs"
+	"github.com/andreyvit/mvp/mvputil"
+)
+
+// jobEnqueueChunkSize is the number of jobs EnqueueBatch writes per transaction.
+var jobEnqueueChunkSize = 1000 // var for tests
+
+// EnqueueBatch is like calling Enqueue for each of ins with the same options,
+// but commits every jobEnqueueChunkSize jobs in a separate transaction.
+// Call it outside of a transaction; within one, all jobs are enqueued
+// in the caller's transaction.
+func (app *App) EnqueueBatch(rc RCish, kind *mvpjobs.Kind, ins []mvpjobs.Params, opts ...any) []*mvpjobs.Job {
+	var eo mvpjobs.EnqueueOptions
+	eo.Apply(opts...)
+	app.validateJobDeps(eo.WaitFor)
+
+	jobs := make([]*mvpjobs.Job, 0, len(ins))
+	mvputil.EnumChunks(ins, jobEnqueueChunkSize, func(chunk []mvpjobs.Params) {
+		rc.BaseRC().MustWrite(func() {
+			for _, in := range chunk {
+				jobs = append(jobs, app.enqueue(rc, kind, in, &eo))
+			}
+		})
+	})
+	return jobs
+}
+
+// batchJobCall adapts the batch handler of the given kind, which has been
+// validated by mvpjobs.Define, for calling with a list of params.
+func batchJobCall(kind *mvpjobs.Kind) func(rc *RC, ins []mvpjobs.Params) ([]error, error) {
+	fv := reflect.ValueOf(kind.BatchHandler)
+	ft := fv.Type()
+	rcFacet := BaseRC.FacetByPtrType(ft.In(0))
+	if rcFacet == nil {
+		panic(fmt.Sprintf("%s: batch handler must accept *RC as first param", kind.Name))
+	}
+	sliceType := ft.In(1)
+
+	return func(rc *RC, ins []mvpjobs.Params) ([]error, error) {
+		slice := reflect.MakeSlice(sliceType, len(ins), len(ins))
+		for i, in := range ins {
+			slice.Index(i).Set(reflect.ValueOf(in))
+		}
+		results := fv.Call([]reflect.Value{reflect.ValueOf(rcFacet.AnyFrom(rc)), slice})
+		errs, _ := results[0].Interface().([]error)
+		err, _ := results[1].Interface().(error)
+		return errs, err
+	}
+}
+
+// runJobBatch executes dequeued jobs of a kind with a batch handler, and
+// records the outcome of each one in a single transaction. Batched jobs
+// cannot be interrupted by CancelJob, they are marked as cancelled once
+// the handler returns.
+func (app *App) runJobBatch(rc *RC, kind *mvpjobs.Kind, jobs []*mvpjobs.Job, workerIdx, workerCount int) {
+	ctx := context.Context(rc)
+	if kind.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, kind.Timeout, errJobTimedOut)
+		defer cancel()
+	}
+	stopHeartbeat := app.startJobHeartbeat(rc, kind, jobs...)
+	errs := app.executeJobBatch(ctx, kind, jobs, workerIdx, workerCount)
+	stopHeartbeat()
+	timedOut := context.Cause(ctx) == errJobTimedOut
+
+	dur := app.Now().Sub(jobs[0].StartTime)
+	for i, j := range jobs {
+		if errs[i] != nil {
+			if timedOut {
+				errs[i] = fmt.Errorf("%w after %v: %v", errJobTimedOut, kind.Timeout, errs[i])
+			}
+			log.Printf("** WARNING: job failed: %s %v %s: %v", j.Kind, j.ID, j.RawParams, errs[i])
+		}
+	}
+
+	rc.MustWrite(func() {
+		for i, j := range jobs {
+			cur := edb.Reload(rc, j)
+			if cur == nil || !cur.Status.IsRunning() || cur.Attempt != j.Attempt {
+				flogger.Log(rc, "job %s %v attempt %d has been reaped while running", j.Kind, j.ID, j.Attempt)
+				continue
+			}
+			if cur.ParamsVersion < j.ParamsVersion {
+				cur.RawParams, cur.ParamsVersion = j.RawParams, j.ParamsVersion // persist upgraded params
+			}
+			app.markJobCompleted(rc, kind, cur, errs[i], dur)
+		}
+	})
+}
+
+// executeJobBatch calls the batch handler of the kind with the params of
+// the given jobs, returning an error for each job. Jobs whose params cannot
+// be decoded are left out of the call.
+func (app *App) executeJobBatch(ctx context.Context, kind *mvpjobs.Kind, jobs []*mvpjobs.Job, workerIdx, workerCount int) []error {
+	errs := make([]error, len(jobs))
+	ins := make([]mvpjobs.Params, 0, len(jobs))
+	indices := make([]int, 0, len(jobs))
+	for i, j := range jobs {
+		in, err := decodeJobParams(kind, j)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		ins = append(ins, in)
+		indices = append(indices, i)
+	}
+	if len(ins) == 0 {
+		return errs
+	}
+
+	jobImpl := app.jobsByKind[kind]
+	if jobImpl == nil || jobImpl.callBatch == nil {
+		panic(fmt.Errorf("no impl registered for job %v", kind.Name))
+	}
+
+	rc := NewRC(ctx, app, fmt.Sprintf("jobs:w%d:%s:batch:%v:%d", workerIdx, kind.Name, jobs[0].ID, len(ins)))
+	defer rc.Close()
+
+	var batchErrs []error
+	err := rc.InTx(kind.Method.StoreAffinity, func() error {
+		var err error
+		batchErrs, err = jobImpl.callBatch(rc, ins)
+		return err
+	})
+	if err == nil && batchErrs != nil && len(batchErrs) != len(ins) {
+		err = fmt.Errorf("batch handler returned %d errors for %d jobs", len(batchErrs), len(ins))
+	}
+	for k, i := range indices {
+		if err != nil {
+			errs[i] = err
+		} else if batchErrs != nil {
+			errs[i] = batchErrs[k]
+		}
+	}
+	return errs
+}
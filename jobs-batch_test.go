@@ -0,0 +1,132 @@
+package mvp_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/mvp"
+	"github.com/andreyvit/mvp/backoff"
+	"github.com/andreyvit/mvp/mvpjobs"
+)
+
+func TestEnqueueBatch(t *testing.T) {
+	defer mvp.SetJobEnqueueChunkSize(2)()
+
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = scm.Define("Import", func(rc *mvp.RC, in *testJobParams) error {
+			return nil
+		}, mvpjobs.Idempotent, mvpjobs.WithPartitionKey(func(in mvpjobs.Params) string {
+			if in.JobName() == "e" {
+				panic("boom")
+			}
+			return ""
+		}))
+	})
+	params := func(names ...string) []mvpjobs.Params {
+		var ins []mvpjobs.Params
+		for _, name := range names {
+			ins = append(ins, &testJobParams{Name: name})
+		}
+		return ins
+	}
+	expectJobs := func(names string, exist bool) {
+		t.Helper()
+		for _, name := range strings.Fields(names) {
+			if j := h.Job(kind, name); (j != nil) != exist {
+				t.Errorf("** job %s exists = %v, wanted %v", name, j != nil, exist)
+			}
+		}
+	}
+
+	rc := mvp.NewRC(h.Ctx, h.App, "test")
+	defer rc.Close()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("** EnqueueBatch didn't panic")
+			}
+		}()
+		h.App.EnqueueBatch(rc, kind, params("a", "b", "c", "d", "e", "f"))
+	}()
+	expectJobs("a b c d", true) // earlier chunks have been committed
+	expectJobs("e f", false)
+
+	// within a transaction, all jobs go into it
+	err := rc.TryWrite(func() error {
+		if jobs := h.App.EnqueueBatch(rc, kind, params("g", "h", "i")); len(jobs) != 3 {
+			t.Errorf("** EnqueueBatch returned %d jobs, wanted 3", len(jobs))
+		}
+		return errors.New("rollback")
+	})
+	if err == nil {
+		t.Fatalf("** TryWrite succeeded")
+	}
+	expectJobs("g h i", false)
+}
+
+func TestJobBatch(t *testing.T) {
+	var batches []string
+	failing := map[string]bool{"b": true}
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = scm.Define("Digest", func(rc *mvp.RC, ins []*testJobParams) ([]error, error) {
+			var names []string
+			errs := make([]error, len(ins))
+			for i, in := range ins {
+				names = append(names, in.Name)
+				if failing[in.Name] {
+					delete(failing, in.Name)
+					errs[i] = errors.New("boom")
+				}
+			}
+			batches = append(batches, strings.Join(names, "+"))
+			return errs, nil
+		}, mvpjobs.Idempotent, mvpjobs.WithBatchSize(2), backoff.Backoff{FixedDelayRetries: 1, FixedDelay: time.Minute})
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "a"})
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "b"})
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "c"})
+	})
+	if n := h.RunDue(); n != 3 {
+		t.Errorf("** RunDue = %d, wanted 3", n)
+	}
+	h.ExpectStatus(kind, "a", mvpjobs.StatusDone, 1)
+	h.ExpectStatus(kind, "b", mvpjobs.StatusRetrying, 1)
+	h.ExpectStatus(kind, "c", mvpjobs.StatusDone, 1)
+	if j := h.Job(kind, "b"); j.LastErr != "boom" {
+		t.Errorf("** LastErr = %q, wanted boom", j.LastErr)
+	}
+
+	h.Advance(time.Minute)
+	h.ExpectStatus(kind, "b", mvpjobs.StatusDone, 2)
+	if a, e := strings.Join(batches, " "), "a+b c b"; a != e {
+		t.Errorf("** batches = %s, wanted %s", a, e)
+	}
+}
+
+func TestJobBatch_handlerError(t *testing.T) {
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = scm.Define("Digest", func(rc *mvp.RC, ins []*testJobParams) ([]error, error) {
+			return nil, errors.New("down")
+		}, mvpjobs.Idempotent, backoff.Backoff{FixedDelayRetries: 1, FixedDelay: time.Minute})
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "a"})
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "b"})
+	})
+	h.RunDue()
+	for _, name := range []string{"a", "b"} {
+		h.ExpectStatus(kind, name, mvpjobs.StatusRetrying, 1)
+		if j := h.Job(kind, name); j.LastErr != "down" {
+			t.Errorf("** %s LastErr = %q, wanted down", name, j.LastErr)
+		}
+	}
+	h.Advance(time.Minute)
+	h.ExpectStatus(kind, "a", mvpjobs.StatusFailed, 2)
+	h.ExpectStatus(kind, "b", mvpjobs.StatusFailed, 2)
+}
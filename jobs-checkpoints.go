@@ -0,0 +1,46 @@
+package mvp
+
+import (
+	"github.com/andreyvit/edb"
+	"github.com/andreyvit/mvp/mvpjobs"
+)
+
+// CurrentJob returns the persistent job whose handler is running in this RC,
+// or nil if this RC isn't running a job.
+func (rc *RC) CurrentJob() *mvpjobs.Job {
+	return rc.job
+}
+
+// JobCheckpoint returns the step saved by a previous attempt of the current job
+// via SaveJobCheckpoint, decoding the saved state into statePtr (which can be
+// nil). Returns an empty step if there's no checkpoint.
+func (rc *RC) JobCheckpoint(statePtr any) (string, error) {
+	if rc.job == nil {
+		panic("JobCheckpoint called outside of a job")
+	}
+	if statePtr != nil {
+		if _, err := rc.job.DecodeState(statePtr); err != nil {
+			return "", err
+		}
+	}
+	return rc.job.Step, nil
+}
+
+// SaveJobCheckpoint records the progress of the current job, to be returned
+// by JobCheckpoint on the next attempt after a failure or a crash. Must be
+// called within a write transaction, so that the checkpoint is committed
+// together with the work it describes. The checkpoint is cleared when
+// the job succeeds.
+func (rc *RC) SaveJobCheckpoint(step string, state any) {
+	if rc.job == nil {
+		panic("SaveJobCheckpoint called outside of a job")
+	}
+	if !rc.IsInWriteTx() {
+		panic("SaveJobCheckpoint must be called within a write transaction")
+	}
+	rc.job.SetState(step, state)
+	if j := edb.Get[mvpjobs.Job](rc, rc.job.ID); j != nil {
+		j.Step, j.RawState = rc.job.Step, rc.job.RawState
+		edb.Put(rc, j)
+	}
+}
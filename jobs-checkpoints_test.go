@@ -0,0 +1,49 @@
+package mvp_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/mvp"
+	"github.com/andreyvit/mvp/backoff"
+	"github.com/andreyvit/mvp/mvpjobs"
+	mvpm "github.com/andreyvit/mvp/mvpmodel"
+)
+
+type testImportState struct {
+	Offset int `json:"offset"`
+}
+
+func TestJobCheckpoint(t *testing.T) {
+	var offsets []int
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = scm.Define("Import", func(rc *mvp.RC, in *testJobParams) error {
+			var state testImportState
+			step, err := rc.JobCheckpoint(&state)
+			if err != nil {
+				return err
+			}
+			offsets = append(offsets, state.Offset)
+			if step == "" {
+				rc.MustWrite(func() {
+					rc.SaveJobCheckpoint("importing", &testImportState{Offset: 100})
+				})
+				return errors.New("interrupted")
+			}
+			return nil
+		}, mvpjobs.Idempotent, backoff.Backoff{ImmediateRetries: 1}, mvpm.Manual)
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "a"})
+	})
+	h.Advance(time.Minute)
+	h.ExpectStatus(kind, "a", mvpjobs.StatusDone, 2)
+	if len(offsets) != 2 || offsets[0] != 0 || offsets[1] != 100 {
+		t.Errorf("** offsets = %v, wanted [0 100]", offsets)
+	}
+	if j := h.Job(kind, "a"); j.Step != "" || j.RawState != nil {
+		t.Errorf("** checkpoint not cleared on success: %q %s", j.Step, j.RawState)
+	}
+}
@@ -0,0 +1,157 @@
+package mvp
+
+import (
+	"net/url"
+	"sort"
+
+	"github.com/andreyvit/edb"
+	"github.com/andreyvit/mvp/flake"
+	"github.com/andreyvit/mvp/httperrors"
+	"github.com/andreyvit/mvp/mvpjobs"
+)
+
+const jobDashboardLimit = 200
+
+// JobDashboardModule returns a module that adds the job dashboard to the given
+// site under the given path. The dashboard lets anyone who can reach it retry
+// and cancel jobs, so pass middleware that only lets admins in.
+func JobDashboardModule(site *Site, path string, middleware ...any) *Module {
+	return &Module{
+		Name: "jobdashboard",
+		SetupHooks: func(app *App) {
+			app.Hooks.SiteRoutes(site, func(b *RouteBuilder) {
+				b.Group(path, func(b *RouteBuilder) {
+					for _, mw := range middleware {
+						b.Use(mw)
+					}
+					JobDashboardRoutes(b)
+				})
+			})
+		},
+	}
+}
+
+// JobDashboardRoutes defines the routes of the job dashboard: a page listing
+// persistent jobs filtered by kind and status along with in-flight ephemeral
+// jobs, and retry and cancel actions. Use JobDashboardModule, or call this
+// within a group protected by admin-only middleware.
+//
+// The page renders the built-in mvp/jobs view within the app's default
+// layout; define views/mvp/jobs.html to replace it. The actions are
+// CSRFProtected.
+func JobDashboardRoutes(b *RouteBuilder) {
+	d := &jobDashboard{}
+	d.list = b.Route("mvp.jobs", "GET /", d.doList)
+	d.retry = b.Route("mvp.jobs.retry", "POST /retry", d.doRetry, CSRFProtected)
+	d.cancel = b.Route("mvp.jobs.cancel", "POST /cancel", d.doCancel, CSRFProtected)
+}
+
+type jobDashboard struct {
+	list, retry, cancel *Route
+}
+
+type jobDashboardFilter struct {
+	Kind   string `json:"kind"`
+	Status string `json:"status"`
+}
+
+type jobDashboardAction struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	Status string `json:"status"`
+}
+
+type jobDashboardPage struct {
+	jobDashboardFilter
+	Kinds     []string
+	Statuses  []mvpjobs.Status
+	Jobs      []*mvpjobs.Job
+	Limit     int
+	Ephemeral []string
+
+	ListPath, RetryPath, CancelPath string
+	CSRFToken                       string
+}
+
+func (d *jobDashboard) doList(rc *RC, in *jobDashboardFilter) (*ViewData, error) {
+	app := rc.app
+	var status mvpjobs.Status
+	if in.Status != "" {
+		var err error
+		status, err = mvpjobs.ParseStatus(in.Status)
+		if err != nil {
+			return nil, httperrors.BadRequest.Wrap(err)
+		}
+	}
+
+	page := &jobDashboardPage{
+		jobDashboardFilter: *in,
+		Jobs:               app.FilterJobs(rc, in.Kind, status, jobDashboardLimit),
+		Limit:              jobDashboardLimit,
+		Ephemeral:          app.EphemeralJobsInFlight(),
+		ListPath:           d.list.Path(),
+		RetryPath:          d.retry.Path(),
+		CancelPath:         d.cancel.Path(),
+		CSRFToken:          rc.CSRFToken(),
+	}
+	for _, kind := range app.JobSchema.Kinds() {
+		if kind.IsPersistent() {
+			page.Kinds = append(page.Kinds, kind.Name)
+		}
+	}
+	sort.Strings(page.Kinds)
+	for s := mvpjobs.StatusQueued; s <= mvpjobs.StatusCancelled; s++ {
+		page.Statuses = append(page.Statuses, s)
+	}
+
+	return &ViewData{
+		View:  "mvp/jobs",
+		Title: "Jobs",
+		Data:  page,
+	}, nil
+}
+
+func (d *jobDashboard) doRetry(rc *RC, in *jobDashboardAction) (*Redirect, error) {
+	j, err := d.findJob(rc, in.ID)
+	if err != nil {
+		return nil, err
+	}
+	kind := rc.app.JobSchema.KindByName(j.Kind)
+	if kind == nil {
+		return nil, httperrors.BadRequest.Msgf("unknown job kind %q", j.Kind)
+	}
+	rc.app.retryJob(rc, kind, j)
+	return d.back(in), nil
+}
+
+func (d *jobDashboard) doCancel(rc *RC, in *jobDashboardAction) (*Redirect, error) {
+	j, err := d.findJob(rc, in.ID)
+	if err != nil {
+		return nil, err
+	}
+	rc.app.cancelJob(rc, j)
+	return d.back(in), nil
+}
+
+func (d *jobDashboard) findJob(rc *RC, idStr string) (*mvpjobs.Job, error) {
+	id, err := flake.Parse(idStr)
+	if err != nil {
+		return nil, httperrors.BadRequest.Wrap(err)
+	}
+	j := edb.Get[mvpjobs.Job](rc, id)
+	if j == nil {
+		return nil, httperrors.NotFound.Msgf("job %v not found", id)
+	}
+	return j, nil
+}
+
+func (d *jobDashboard) back(in *jobDashboardAction) *Redirect {
+	values := make(url.Values)
+	if in.Kind != "" {
+		values.Set("kind", in.Kind)
+	}
+	if in.Status != "" {
+		values.Set("status", in.Status)
+	}
+	return &Redirect{Path: d.list.Path(), Values: values}
+}
@@ -0,0 +1,78 @@
+package mvp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/andreyvit/mvp"
+	"github.com/andreyvit/mvp/mvpjobs"
+	"github.com/andreyvit/mvp/mvpjobstest"
+)
+
+func newDashboardTestApp(t *testing.T, define func(scm *mvpjobs.Schema)) *mvpjobstest.Harness {
+	return newTestApp(t, define, func(settings *mvp.Settings) {
+		settings.BaseURL = testBaseURL
+		settings.AllowInsecureHttp = true
+		settings.Configuration.Modules = append(settings.Configuration.Modules, mvp.JobDashboardModule(mvp.DefaultSite, "/jobs"))
+	}, withViews(t, map[string]string{
+		"layouts/default.html": `<title>{{.Title}}</title>{{.Content}}`,
+	}))
+}
+
+func TestJobDashboard(t *testing.T) {
+	var kind *mvpjobs.Kind
+	h := newDashboardTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = scm.Define("Noop", func(rc *mvp.RC, in *testJobParams) error {
+			return nil
+		}, mvpjobs.Idempotent)
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "a"})
+	})
+	id := h.Job(kind, "a").ID.String()
+
+	w := serve(h, httptest.NewRequest("GET", "http://example.com/jobs/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("** GET = %d, body:\n%s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "<title>Jobs</title>") || !strings.Contains(body, ">"+id+"<") {
+		t.Errorf("** page does not list the job within the layout:\n%s", body)
+	}
+	var csrfCookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "csrf" {
+			csrfCookie = c
+		}
+	}
+	if csrfCookie == nil {
+		t.Fatalf("** no csrf cookie set")
+	}
+	if !strings.Contains(body, `name="_csrf" value="`+csrfCookie.Value+`"`) {
+		t.Errorf("** forms lack the CSRF token:\n%s", body)
+	}
+
+	cancel := func(token string) *httptest.ResponseRecorder {
+		form := url.Values{"id": {id}, "_csrf": {token}}
+		r := httptest.NewRequest("POST", "http://example.com/jobs/cancel", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.AddCookie(csrfCookie)
+		return serve(h, r)
+	}
+
+	if w := cancel(""); w.Code != http.StatusForbidden {
+		t.Errorf("** POST without token = %d, wanted 403", w.Code)
+	}
+	if w := cancel("0123456789abcdef0123456789abcdef"); w.Code != http.StatusForbidden {
+		t.Errorf("** POST with wrong token = %d, wanted 403", w.Code)
+	}
+	h.ExpectStatus(kind, "a", mvpjobs.StatusQueued, 0)
+
+	if w := cancel(csrfCookie.Value); w.Code != http.StatusSeeOther {
+		t.Fatalf("** POST with token = %d, wanted 303, body:\n%s", w.Code, w.Body.String())
+	}
+	h.ExpectStatus(kind, "a", mvpjobs.StatusCancelled, 0)
+}
@@ -0,0 +1,105 @@
+package mvp
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/andreyvit/edb"
+	"github.com/andreyvit/mvp/mvpjobs"
+)
+
+func (app *App) validateJobDeps(deps []mvpjobs.KindName) {
+	for _, kn := range deps {
+		kind := app.JobSchema.KindByName(kn.Kind)
+		if kind == nil {
+			panic(fmt.Errorf("cannot wait for job of unknown kind %q", kn.Kind))
+		}
+		if kind.AllowNames() && kn.IsAnonymous() {
+			panic(fmt.Errorf("cannot wait for anonymous job of kind %q", kn.Kind))
+		}
+		if kind.Behavior != mvpjobs.Idempotent || kind.RepeatInterval > 0 || kind.Schedule != nil {
+			panic(fmt.Errorf("cannot wait for job of kind %q, %v jobs never stay done", kn.Kind, kind.Behavior))
+		}
+	}
+}
+
+func (app *App) jobByKindName(txish edb.Txish, kn mvpjobs.KindName) *mvpjobs.Job {
+	kind := app.JobSchema.KindByName(kn.Kind)
+	if kind == nil {
+		return nil
+	}
+	return app.Job(txish, kind, kn.Name)
+}
+
+// updateBlockedJob sets the status of a job with dependencies to queued once
+// all of them are done, to skipped if any has failed or doesn't exist,
+// and to blocked otherwise.
+func (app *App) updateBlockedJob(rc RCish, j *mvpjobs.Job) {
+	status := mvpjobs.StatusQueued
+	for _, kn := range j.Deps {
+		dep := app.jobByKindName(rc, kn)
+		if dep == nil {
+			skipBlockedJob(rc, j, fmt.Sprintf("dependency %v not found", kn))
+			return
+		}
+		switch dep.Status {
+		case mvpjobs.StatusDone:
+			break
+		case mvpjobs.StatusFailed, mvpjobs.StatusFailedSkipped, mvpjobs.StatusCancelled:
+			skipBlockedJob(rc, j, fmt.Sprintf("dependency %v %v", kn, dep.Status))
+			return
+		default:
+			status = mvpjobs.StatusBlocked
+		}
+	}
+
+	// blocked jobs keep their requested run time, it's not indexed until they're queued
+	j.Status = status
+	if status == mvpjobs.StatusQueued && j.NextRunTime.IsZero() {
+		j.NextRunTime = rc.Now()
+	}
+}
+
+func skipBlockedJob(rc RCish, j *mvpjobs.Job, reason string) {
+	j.Status = mvpjobs.StatusFailedSkipped
+	j.NextRunTime = time.Time{}
+	j.FinishTime = rc.Now()
+	j.LastErr = reason
+}
+
+// addJobDeps makes a job that is enqueued again wait for the given jobs too,
+// unless it's running, in which case they apply to the run requested by
+// the re-enqueue. Panics if the job has no run left to block.
+func (app *App) addJobDeps(rc RCish, j *mvpjobs.Job, deps []mvpjobs.KindName) {
+	if len(deps) == 0 {
+		return
+	}
+	if !j.Status.IsPending() && j.Status != mvpjobs.StatusBlocked && j.Status != mvpjobs.StatusRunningPending {
+		panic(fmt.Errorf("%v: cannot wait for other jobs, the job is %v", j.KindName(), j.Status))
+	}
+	for _, kn := range deps {
+		if !slices.Contains(j.Deps, kn) {
+			j.Deps = append(j.Deps, kn)
+		}
+	}
+	if j.Status != mvpjobs.StatusRunningPending {
+		app.updateBlockedJob(rc, j)
+	}
+	edb.Put(rc, j)
+	app.unblockDependentJobs(rc, j)
+}
+
+// unblockDependentJobs re-evaluates the jobs waiting for the given one after
+// it reaches a terminal status. Skipping a job cascades to its own dependents.
+func (app *App) unblockDependentJobs(rc RCish, j *mvpjobs.Job) {
+	if !j.Status.IsTerminal() {
+		return
+	}
+	blocked := edb.All(edb.ExactIndexScan[mvpjobs.Job](rc, blockedJobsByDep, j.KindName()))
+	for _, b := range blocked {
+		app.updateBlockedJob(rc, b)
+		edb.Put(rc, b)
+		app.unblockDependentJobs(rc, b)
+	}
+}
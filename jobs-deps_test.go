@@ -0,0 +1,126 @@
+package mvp_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/mvp"
+	"github.com/andreyvit/mvp/mvpjobs"
+	"github.com/andreyvit/mvp/mvpjobstest"
+)
+
+type depsTestKinds struct {
+	Import, Reindex, Notify, Repeatable, Cron *mvpjobs.Kind
+}
+
+func newDepsTestApp(t *testing.T, failImport bool) (*depsTestKinds, *mvpjobstest.Harness) {
+	var k depsTestKinds
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		k.Import = scm.Define("Import", func(rc *mvp.RC, in *testJobParams) error {
+			if failImport {
+				return errors.New("boom")
+			}
+			return nil
+		}, mvpjobs.Idempotent)
+		k.Reindex = scm.Define("Reindex", func(rc *mvp.RC, in *testJobParams) error {
+			return nil
+		}, mvpjobs.Idempotent)
+		k.Notify = scm.Define("Notify", func(rc *mvp.RC, in *testJobParams) error {
+			return nil
+		}, mvpjobs.Idempotent)
+		k.Repeatable = scm.Define("Sync", func(rc *mvp.RC, in *testJobParams) error {
+			return nil
+		}, mvpjobs.Repeatable)
+		k.Cron = scm.Define("Cleanup", func(rc *mvp.RC, in *mvpjobs.NoParams) error {
+			return nil
+		}, mvpjobs.Cron, mvpjobs.WithRepeatInterval(time.Hour))
+	})
+	return &k, h
+}
+
+func TestWaitFor_pipeline(t *testing.T) {
+	k, h := newDepsTestApp(t, false)
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, k.Import, &testJobParams{Name: "a"})
+		h.App.Enqueue(rc, k.Reindex, &testJobParams{Name: "a"}, mvpjobs.WaitFor{k.Import.KindName("a")})
+		h.App.Enqueue(rc, k.Notify, &testJobParams{Name: "a"}, mvpjobs.WaitFor{k.Reindex.KindName("a")})
+	})
+	h.ExpectStatus(k.Reindex, "a", mvpjobs.StatusBlocked, 0)
+	h.ExpectStatus(k.Notify, "a", mvpjobs.StatusBlocked, 0)
+
+	if n := h.RunDue(); n != 3 {
+		t.Errorf("** RunDue = %d, wanted 3", n)
+	}
+	h.ExpectStatus(k.Import, "a", mvpjobs.StatusDone, 1)
+	h.ExpectStatus(k.Reindex, "a", mvpjobs.StatusDone, 1)
+	h.ExpectStatus(k.Notify, "a", mvpjobs.StatusDone, 1)
+}
+
+func TestWaitFor_failedDependency(t *testing.T) {
+	k, h := newDepsTestApp(t, true)
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, k.Import, &testJobParams{Name: "a"})
+		h.App.Enqueue(rc, k.Reindex, &testJobParams{Name: "a"}, mvpjobs.WaitFor{k.Import.KindName("a")})
+		h.App.Enqueue(rc, k.Notify, &testJobParams{Name: "a"}, mvpjobs.WaitFor{k.Reindex.KindName("a")})
+	})
+	h.Advance(24 * time.Hour)
+	h.ExpectStatus(k.Import, "a", mvpjobs.StatusFailed, 1)
+	h.ExpectStatus(k.Reindex, "a", mvpjobs.StatusFailedSkipped, 0)
+	h.ExpectStatus(k.Notify, "a", mvpjobs.StatusFailedSkipped, 0)
+	if e, a := "dependency Import:a failed", h.Job(k.Reindex, "a").LastErr; a != e {
+		t.Errorf("** LastErr = %q, wanted %q", a, e)
+	}
+}
+
+func TestWaitFor_missingDependency(t *testing.T) {
+	k, h := newDepsTestApp(t, false)
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, k.Reindex, &testJobParams{Name: "a"}, mvpjobs.WaitFor{k.Import.KindName("a")})
+	})
+	h.ExpectStatus(k.Reindex, "a", mvpjobs.StatusFailedSkipped, 0)
+	if e, a := "dependency Import:a not found", h.Job(k.Reindex, "a").LastErr; a != e {
+		t.Errorf("** LastErr = %q, wanted %q", a, e)
+	}
+}
+
+func TestWaitFor_repeatingDependency(t *testing.T) {
+	k, h := newDepsTestApp(t, false)
+	for _, dep := range []mvpjobs.KindName{k.Repeatable.KindName("a"), k.Cron.KindName("")} {
+		func() {
+			defer func() {
+				if e := recover(); e == nil {
+					t.Errorf("** WaitFor %v did not panic", dep)
+				}
+			}()
+			mustWrite(h, func(rc *mvp.RC) {
+				h.App.Enqueue(rc, k.Reindex, &testJobParams{Name: "a"}, mvpjobs.WaitFor{dep})
+			})
+		}()
+	}
+}
+
+func TestWaitFor_reenqueue(t *testing.T) {
+	k, h := newDepsTestApp(t, false)
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.EnqueueAfter(rc, k.Reindex, &testJobParams{Name: "a"}, time.Hour)
+		h.App.Enqueue(rc, k.Import, &testJobParams{Name: "a"}, mvpjobs.WithRunTime(testStartTime.Add(2*time.Hour)))
+		h.App.Enqueue(rc, k.Reindex, &testJobParams{Name: "a"}, mvpjobs.WaitFor{k.Import.KindName("a")})
+	})
+	h.ExpectStatus(k.Reindex, "a", mvpjobs.StatusBlocked, 0)
+
+	h.Advance(90 * time.Minute)
+	h.ExpectStatus(k.Reindex, "a", mvpjobs.StatusBlocked, 0)
+	h.Advance(time.Hour)
+	h.ExpectStatus(k.Import, "a", mvpjobs.StatusDone, 1)
+	h.ExpectStatus(k.Reindex, "a", mvpjobs.StatusDone, 1)
+
+	defer func() {
+		if e := recover(); e == nil {
+			t.Errorf("** WaitFor on a finished job did not panic")
+		}
+	}()
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, k.Reindex, &testJobParams{Name: "a"}, mvpjobs.WaitFor{k.Import.KindName("a")})
+	})
+}
@@ -2,34 +2,137 @@ package mvp
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"reflect"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/andreyvit/mvp/flogger"
 	"github.com/andreyvit/mvp/mvpjobs"
+	"golang.org/x/exp/maps"
 )
 
+const defaultEphemeralDrainTimeout = 10 * time.Second
+
+// ErrEphemeralQueueFull is returned by EnqueueEphemeral when the queue is full
+// and the kind's Overflow policy gives up on the job.
+var ErrEphemeralQueueFull = errors.New("ephemeral job queue is full")
+
 type EphemeralJobQueue struct {
-	mut sync.Mutex
-	m   map[string]bool
-	q   chan *EphemeralJob
+	mut     sync.Mutex
+	m       map[string]bool
+	counts  map[string]int // queued or running jobs by kind
+	queue   []*EphemeralJob
+	maxSize int
+	changed chan struct{}  // closed and replaced whenever queue changes
+	spills  sync.WaitGroup // background spills, waited for on shutdown
 }
 
 type EphemeralJob struct {
-	Kind *mvpjobs.Kind
-	Key  string
-	F    func(rc *RC) error
+	Kind   *mvpjobs.Kind
+	Key    string
+	F      func(rc *RC) error
+	Params mvpjobs.Params // params of the WithSpillTo job, if any
 }
 
 func (app *App) initEphemeralJobs() {
 	app.ephemeralJobQueue.m = make(map[string]bool)
-	app.ephemeralJobQueue.q = make(chan *EphemeralJob, app.Settings.EphemeralQueueMaxSize)
+	app.ephemeralJobQueue.counts = make(map[string]int)
+	app.ephemeralJobQueue.maxSize = max(app.Settings.EphemeralQueueMaxSize, 1)
+	app.ephemeralJobQueue.changed = make(chan struct{})
+	ephemeralQueueCapacityMetric.Set(int64(app.ephemeralJobQueue.maxSize))
+}
+
+// tryPush appends the job unless the queue is full (or force is set).
+// On failure, it returns a channel that is closed when the queue changes.
+func (eq *EphemeralJobQueue) tryPush(job *EphemeralJob, force bool) (bool, <-chan struct{}) {
+	eq.mut.Lock()
+	defer eq.mut.Unlock()
+	if len(eq.queue) >= eq.maxSize && !force {
+		return false, eq.changed
+	}
+	eq.queue = append(eq.queue, job)
+	eq.notifyLocked()
+	return true, nil
 }
 
-func (app *App) EnqueueEphemeral(kind *mvpjobs.Kind, name string, f func(rc *RC) error) {
+// pushReplacingOldest appends the job, making room by removing the oldest
+// queued job of the same kind if the queue is full. Returns the removed job,
+// or the job itself if there was nothing of its kind to remove.
+func (eq *EphemeralJobQueue) pushReplacingOldest(job *EphemeralJob) *EphemeralJob {
+	eq.mut.Lock()
+	defer eq.mut.Unlock()
+	var dropped *EphemeralJob
+	if len(eq.queue) >= eq.maxSize {
+		i := slices.IndexFunc(eq.queue, func(j *EphemeralJob) bool { return j.Kind == job.Kind })
+		if i < 0 {
+			return job
+		}
+		dropped = eq.queue[i]
+		eq.queue = slices.Delete(eq.queue, i, i+1)
+	}
+	eq.queue = append(eq.queue, job)
+	eq.notifyLocked()
+	return dropped
+}
+
+// tryPop removes the oldest queued job. If the queue is empty, it returns
+// a channel that is closed when the queue changes.
+func (eq *EphemeralJobQueue) tryPop() (*EphemeralJob, <-chan struct{}) {
+	eq.mut.Lock()
+	defer eq.mut.Unlock()
+	if len(eq.queue) == 0 {
+		return nil, eq.changed
+	}
+	job := eq.queue[0]
+	eq.queue[0] = nil
+	eq.queue = eq.queue[1:]
+	eq.notifyLocked()
+	return job, nil
+}
+
+func (eq *EphemeralJobQueue) len() int {
+	eq.mut.Lock()
+	defer eq.mut.Unlock()
+	return len(eq.queue)
+}
+
+func (eq *EphemeralJobQueue) notifyLocked() {
+	close(eq.changed)
+	eq.changed = make(chan struct{})
+}
+
+// EnqueueEphemeral queues f to run on an ephemeral worker, unless a job of
+// the same kind and name is already queued or running. When the queue is
+// full, the kind's Overflow policy decides what happens; ErrEphemeralQueueFull
+// is returned if the job has been turned away.
+//
+// Pass the params of the WithSpillTo kind in opts for kinds that spill.
+// Spilled jobs are enqueued in a separate transaction shortly after;
+// Close waits for them.
+func (app *App) EnqueueEphemeral(kind *mvpjobs.Kind, name string, f func(rc *RC) error, opts ...any) error {
 	if kind.Persistence != mvpjobs.Ephemeral {
 		panic("EnqueueEphemeral requires an ephemeral job")
 	}
+	var params mvpjobs.Params
+	for _, opt := range opts {
+		switch opt := opt.(type) {
+		case mvpjobs.Params:
+			params = opt
+		default:
+			panic(fmt.Errorf("%s: unknown EnqueueEphemeral option %T %v", kind.Name, opt, opt))
+		}
+	}
+	if kind.Overflow == mvpjobs.OverflowSpill {
+		spillKind := app.JobSchema.KindByName(kind.SpillTo)
+		if params == nil || reflect.TypeOf(params) != spillKind.Method.InPtrType {
+			panic(fmt.Errorf("%s: EnqueueEphemeral requires %v params to spill into %s", kind.Name, spillKind.Method.InPtrType, spillKind.Name))
+		}
+	}
 
 	var key string
 	if name == "" {
@@ -39,19 +142,124 @@ func (app *App) EnqueueEphemeral(kind *mvpjobs.Kind, name string, f func(rc *RC)
 	}
 
 	if !app.startEphemeralJob(kind, key) {
-		return
+		return nil
 	}
-	job := &EphemeralJob{kind, key, f}
+	job := &EphemeralJob{kind, key, f, params}
 	if app.Settings.IsTesting {
 		rc := NewRC(context.Background(), app, "ejobs:inline")
 		defer rc.Close()
 		// TODO: better context?
 		app.runEphemeralJob(rc, job)
-	} else {
-		app.ephemeralJobQueue.q <- job
+		return nil
+	}
+	return app.pushEphemeralJob(job, false)
+}
+
+// pushEphemeralJob adds a started job to the queue, applying the kind's
+// Overflow policy if the queue is full. A repeat is pushed by the worker that
+// has just run the job, so it never waits for space.
+func (app *App) pushEphemeralJob(job *EphemeralJob, repeat bool) error {
+	eq := &app.ephemeralJobQueue
+	defer app.updateEphemeralJobMetrics()
+	ok, changed := eq.tryPush(job, false)
+	if ok {
+		return nil
+	}
+
+	kind := job.Kind
+	switch kind.Overflow {
+	case mvpjobs.OverflowBlock:
+		if repeat {
+			eq.tryPush(job, true)
+			ephemeralJobsOverflowMetric.Inc(kind.Name, "queued")
+			return nil
+		}
+		var timeout <-chan time.Time
+		if kind.OverflowTimeout > 0 {
+			timer := time.NewTimer(kind.OverflowTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		for !ok {
+			select {
+			case <-changed:
+				ok, changed = eq.tryPush(job, false)
+			case <-timeout:
+				app.abandonEphemeralJob(job, "rejected")
+				return ErrEphemeralQueueFull
+			}
+		}
+		ephemeralJobsOverflowMetric.Inc(kind.Name, "queued")
+		return nil
+	case mvpjobs.OverflowDropOldest:
+		old := eq.pushReplacingOldest(job)
+		if old != nil {
+			log.Printf("** WARNING: ephemeral queue full, dropped %s", old.Key)
+			app.abandonEphemeralJob(old, "dropped")
+		}
+		if old == job {
+			return ErrEphemeralQueueFull
+		}
+		return nil
+	case mvpjobs.OverflowReject:
+		app.abandonEphemeralJob(job, "rejected")
+		return ErrEphemeralQueueFull
+	case mvpjobs.OverflowSpill:
+		app.abandonEphemeralJob(job, "spilled")
+		eq.spills.Add(1)
+		go func() {
+			defer eq.spills.Done()
+			app.spillEphemeralJob(job)
+		}()
+		return nil
+	default:
+		panic(fmt.Errorf("%s: unknown overflow policy %v", kind.Name, kind.Overflow))
+	}
+}
+
+// spillEphemeralJob enqueues the WithSpillTo persistent job in place of
+// an ephemeral one. The error is logged here because spilling usually
+// happens in the background.
+func (app *App) spillEphemeralJob(job *EphemeralJob) error {
+	rc := NewRC(context.Background(), app, "ejobs:spill")
+	defer rc.Close()
+	rc.RequestID = job.Key
+
+	kind := app.JobSchema.KindByName(job.Kind.SpillTo)
+	err := rc.TryWrite(func() error {
+		app.Enqueue(rc, kind, job.Params)
+		return nil
+	})
+	if err != nil {
+		flogger.Log(rc, "ERROR: failed to spill ephemeral job into %s: %v", kind.Name, err)
+		return err
 	}
+	flogger.Log(rc, "ephemeral job spilled into %s", kind.Name)
+	return nil
 }
 
+// EphemeralJobsInFlight returns the keys of the ephemeral jobs that are queued
+// or running, sorted.
+func (app *App) EphemeralJobsInFlight() []string {
+	app.ephemeralJobQueue.mut.Lock()
+	defer app.ephemeralJobQueue.mut.Unlock()
+	keys := maps.Keys(app.ephemeralJobQueue.m)
+	sort.Strings(keys)
+	return keys
+}
+
+// EphemeralJobCounts returns the number of queued or running ephemeral jobs
+// by kind name.
+func (app *App) EphemeralJobCounts() map[string]int {
+	app.ephemeralJobQueue.mut.Lock()
+	defer app.ephemeralJobQueue.mut.Unlock()
+	return maps.Clone(app.ephemeralJobQueue.counts)
+}
+
+// StartEphemeralJobWorkers starts count ephemeral workers. When ctx is
+// cancelled, the workers keep running queued jobs for up to
+// EphemeralDrainTimeout; jobs still queued after that are spilled if their
+// kind allows it, and logged as lost otherwise.
 func (app *App) StartEphemeralJobWorkers(ctx context.Context, count int, quitf func(err error)) {
 	if count == 0 {
 		return
@@ -64,6 +272,8 @@ func (app *App) StartEphemeralJobWorkers(ctx context.Context, count int, quitf f
 	}
 	go func() {
 		wg.Wait()
+		app.discardEphemeralJobs()
+		app.ephemeralJobQueue.spills.Wait()
 		quitf(nil)
 	}()
 }
@@ -73,17 +283,74 @@ func (app *App) runEphemeralJobsContinuously(ctx context.Context, workerIdx, wor
 	rc := NewRC(ctx, app, fmt.Sprintf("ejobs:w%d", workerIdx))
 	defer rc.Close()
 	for ctx.Err() == nil {
-		select {
-		case job := <-app.ephemeralJobQueue.q:
-			app.runEphemeralJob(rc, job)
-		case <-ctx.Done():
-			break
+		job, changed := app.ephemeralJobQueue.tryPop()
+		if job == nil {
+			select {
+			case <-changed:
+			case <-ctx.Done():
+			}
+			continue
+		}
+		app.updateEphemeralJobMetrics()
+		app.runEphemeralJob(rc, job)
+	}
+
+	app.drainEphemeralJobs(workerIdx)
+}
+
+// drainEphemeralJobs runs the jobs remaining in the queue after shutdown has
+// been requested, until the queue is empty or EphemeralDrainTimeout expires.
+func (app *App) drainEphemeralJobs(workerIdx int) {
+	timeout := app.Settings.EphemeralDrainTimeout.Value()
+	if timeout == 0 {
+		timeout = defaultEphemeralDrainTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	rc := NewRC(ctx, app, fmt.Sprintf("ejobs:w%d:drain", workerIdx))
+	defer rc.Close()
+	for ctx.Err() == nil {
+		job, _ := app.ephemeralJobQueue.tryPop()
+		if job == nil {
+			return
+		}
+		app.updateEphemeralJobMetrics()
+		app.runEphemeralJob(rc, job)
+	}
+}
+
+// discardEphemeralJobs empties the queue once all workers have stopped.
+func (app *App) discardEphemeralJobs() {
+	for {
+		job, _ := app.ephemeralJobQueue.tryPop()
+		if job == nil {
+			app.updateEphemeralJobMetrics()
+			return
+		}
+		if job.Kind.SpillTo != "" {
+			app.abandonEphemeralJob(job, "spilled")
+			app.spillEphemeralJob(job)
+		} else {
+			log.Printf("** WARNING: ephemeral job %s lost on shutdown", job.Key)
+			app.abandonEphemeralJob(job, "lost")
 		}
 	}
 }
 
+// runEphemeralJob runs the job, and then queues it again if it has been
+// re-enqueued while running.
 func (app *App) runEphemeralJob(rc *RC, job *EphemeralJob) {
-	defer app.finishEphemeralJob(job)
+	for app.runEphemeralJobOnce(rc, job) {
+		if !app.Settings.IsTesting {
+			app.pushEphemeralJob(job, true)
+			return
+		}
+	}
+}
+
+func (app *App) runEphemeralJobOnce(rc *RC, job *EphemeralJob) (repeat bool) {
+	defer func() { repeat = app.finishEphemeralJob(job) }()
 
 	rc.RequestID = job.Key
 	defer func() { rc.RequestID = "" }()
@@ -97,6 +364,7 @@ func (app *App) runEphemeralJob(rc *RC, job *EphemeralJob) {
 	} else {
 		flogger.Log(rc, "ephemeral job finished")
 	}
+	return false // set by the deferred finishEphemeralJob
 }
 
 func (app *App) startEphemeralJob(kind *mvpjobs.Kind, key string) bool {
@@ -109,18 +377,50 @@ func (app *App) startEphemeralJob(kind *mvpjobs.Kind, key string) bool {
 		return false
 	}
 	app.ephemeralJobQueue.m[key] = false
+	app.ephemeralJobQueue.counts[kind.Name]++
+	ephemeralJobsInFlightMetric.Set(int64(app.ephemeralJobQueue.counts[kind.Name]), kind.Name)
 	return true
 }
 
-func (app *App) finishEphemeralJob(job *EphemeralJob) {
+// finishEphemeralJob forgets a job that has finished running, unless it has
+// been re-enqueued meanwhile, in which case it returns true.
+func (app *App) finishEphemeralJob(job *EphemeralJob) bool {
 	app.ephemeralJobQueue.mut.Lock()
 	defer app.ephemeralJobQueue.mut.Unlock()
 
 	if job.Kind.Behavior.IsRepeatable() && app.ephemeralJobQueue.m[job.Key] {
-		go func() {
-			app.ephemeralJobQueue.q <- job
-		}()
+		app.ephemeralJobQueue.m[job.Key] = false
+		return true
 	}
 
+	app.removeEphemeralJobLocked(job)
+	return false
+}
+
+// abandonEphemeralJob forgets a job that won't run, including any pending
+// repeat, and counts it with the given overflow outcome.
+func (app *App) abandonEphemeralJob(job *EphemeralJob, outcome string) {
+	app.ephemeralJobQueue.mut.Lock()
+	defer app.ephemeralJobQueue.mut.Unlock()
+	app.removeEphemeralJobLocked(job)
+	ephemeralJobsOverflowMetric.Inc(job.Kind.Name, outcome)
+}
+
+func (app *App) removeEphemeralJobLocked(job *EphemeralJob) {
 	delete(app.ephemeralJobQueue.m, job.Key)
+	kindName := job.Kind.Name
+	if app.ephemeralJobQueue.counts[kindName]--; app.ephemeralJobQueue.counts[kindName] <= 0 {
+		delete(app.ephemeralJobQueue.counts, kindName)
+	}
+	ephemeralJobsInFlightMetric.Set(int64(app.ephemeralJobQueue.counts[kindName]), kindName)
+}
+
+func (app *App) checkEphemeralSpillKind(kind *mvpjobs.Kind) {
+	if kind.SpillTo == "" {
+		return
+	}
+	spillKind := app.JobSchema.KindByName(kind.SpillTo)
+	if spillKind == nil || !spillKind.IsPersistent() {
+		panic(fmt.Errorf("%s: WithSpillTo(%q) must name a persistent job kind", kind.Name, kind.SpillTo))
+	}
 }
@@ -0,0 +1,160 @@
+package mvp_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/andreyvit/mvp"
+	"github.com/andreyvit/mvp/mvpjobs"
+)
+
+// ephemeralLog records which ephemeral jobs have run.
+type ephemeralLog struct {
+	mut sync.Mutex
+	ran []string
+}
+
+func (l *ephemeralLog) job(name string) func(rc *mvp.RC) error {
+	return func(rc *mvp.RC) error {
+		l.mut.Lock()
+		defer l.mut.Unlock()
+		l.ran = append(l.ran, name)
+		return nil
+	}
+}
+
+func (l *ephemeralLog) expect(t testing.TB, expected ...string) {
+	t.Helper()
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	if !reflect.DeepEqual(l.ran, expected) {
+		t.Errorf("** ran %q, wanted %q", l.ran, expected)
+	}
+}
+
+// stopEphemeralWorkers stops the workers started with the given ctx cancel
+// func, and waits for them to drain the queue.
+func stopEphemeralWorkers(cancel func(), done <-chan struct{}) {
+	cancel()
+	<-done
+}
+
+func startEphemeralWorkers(app *mvp.App, count int) (cancel func(), done <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	quit := make(chan struct{})
+	app.StartEphemeralJobWorkers(ctx, count, func(err error) { close(quit) })
+	return cancel, quit
+}
+
+func expectInFlight(t testing.TB, app *mvp.App, expected ...string) {
+	t.Helper()
+	if actual := app.EphemeralJobsInFlight(); len(actual) != len(expected) || len(actual) > 0 && !reflect.DeepEqual(actual, expected) {
+		t.Errorf("** EphemeralJobsInFlight = %q, wanted %q", actual, expected)
+	}
+}
+
+func TestEphemeralJobs_dropOldest(t *testing.T) {
+	var drop, drop2, other *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		drop = scm.Define("Drop", nil, mvpjobs.Idempotent, mvpjobs.Ephemeral, mvpjobs.OverflowDropOldest)
+		drop2 = scm.Define("Drop2", nil, mvpjobs.Idempotent, mvpjobs.Ephemeral, mvpjobs.OverflowDropOldest)
+		other = scm.Define("Other", nil, mvpjobs.Idempotent, mvpjobs.Ephemeral, mvpjobs.OverflowReject)
+	}, func(settings *mvp.Settings) {
+		settings.EphemeralQueueMaxSize = 2
+	})
+	app := h.App
+	var log ephemeralLog
+
+	for _, err := range []error{
+		app.EnqueueEphemeral(other, "1", log.job("other1")),
+		app.EnqueueEphemeral(drop, "1", log.job("drop1")),
+		app.EnqueueEphemeral(drop, "2", log.job("drop2")),
+	} {
+		if err != nil {
+			t.Fatalf("** EnqueueEphemeral failed: %v", err)
+		}
+	}
+	expectInFlight(t, app, "Drop:2", "Other:1")
+
+	// nothing of this kind to drop, so the new job is the one turned away
+	err := app.EnqueueEphemeral(drop2, "1", log.job("drop2_1"))
+	if !errors.Is(err, mvp.ErrEphemeralQueueFull) {
+		t.Errorf("** EnqueueEphemeral = %v, wanted ErrEphemeralQueueFull", err)
+	}
+	expectInFlight(t, app, "Drop:2", "Other:1")
+
+	stopEphemeralWorkers(startEphemeralWorkers(app, 1))
+	log.expect(t, "other1", "drop2")
+	expectInFlight(t, app)
+}
+
+func TestEphemeralJobs_repeatOverflow(t *testing.T) {
+	var rep, fill *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		rep = scm.Define("Rep", nil, mvpjobs.Repeatable, mvpjobs.Ephemeral, mvpjobs.OverflowReject)
+		fill = scm.Define("Fill", nil, mvpjobs.Idempotent, mvpjobs.Ephemeral, mvpjobs.OverflowReject)
+	}, func(settings *mvp.Settings) {
+		settings.EphemeralQueueMaxSize = 1
+	})
+	app := h.App
+	var log ephemeralLog
+
+	started, release := make(chan struct{}), make(chan struct{})
+	repJob := log.job("rep")
+	err := app.EnqueueEphemeral(rep, "", func(rc *mvp.RC) error {
+		close(started)
+		<-release
+		return repJob(rc)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel, done := startEphemeralWorkers(app, 1)
+	<-started
+
+	// ask for a repeat, then fill the queue so that the repeat overflows
+	if err := app.EnqueueEphemeral(rep, "", log.job("rep_again")); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.EnqueueEphemeral(fill, "", log.job("fill")); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+
+	stopEphemeralWorkers(cancel, done)
+	log.expect(t, "rep", "fill")
+	expectInFlight(t, app)
+}
+
+func TestEphemeralJobs_spill(t *testing.T) {
+	var spilled, sp *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		spilled = scm.Define("Spilled", func(rc *mvp.RC, in *testJobParams) error { return nil }, mvpjobs.Idempotent)
+		sp = scm.Define("Sp", nil, mvpjobs.Idempotent, mvpjobs.Ephemeral, mvpjobs.OverflowSpill, mvpjobs.WithSpillTo("Spilled"))
+	}, func(settings *mvp.Settings) {
+		settings.EphemeralQueueMaxSize = 1
+	})
+	app := h.App
+	var log ephemeralLog
+
+	if err := app.EnqueueEphemeral(sp, "1", log.job("sp1"), &testJobParams{Name: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.EnqueueEphemeral(sp, "2", log.job("sp2"), &testJobParams{Name: "2"}); err != nil {
+		t.Fatal(err)
+	}
+	expectInFlight(t, app, "Sp:1")
+
+	app.WaitForEphemeralSpills()
+	if j := h.Job(spilled, "1"); j != nil {
+		t.Errorf("** queued job spilled: %v", j)
+	}
+	h.ExpectStatus(spilled, "2", mvpjobs.StatusQueued, 0)
+
+	stopEphemeralWorkers(startEphemeralWorkers(app, 1))
+	log.expect(t, "sp1")
+	expectInFlight(t, app)
+}
@@ -0,0 +1,105 @@
+package mvp
+
+import (
+	"context"
+	"time"
+
+	"github.com/andreyvit/edb"
+	"github.com/andreyvit/mvp/mvpjobs"
+	"github.com/andreyvit/mvp/mvpmetrics"
+)
+
+const jobMetricsInterval = 15 * time.Second
+
+var (
+	jobsQueuedMetric = mvpmetrics.NewGauge("mvp_jobs", []string{"kind", "status"},
+		mvpmetrics.Help("Number of non-finished persistent jobs by kind and status"))
+	jobsCompletedMetric = mvpmetrics.NewCounter("mvp_jobs_completed_total", []string{"kind", "outcome"},
+		mvpmetrics.Help("Number of persistent job runs by kind and outcome (succeeded, retrying, failed, cancelled)"))
+	jobDurationMetric = mvpmetrics.NewHistogram("mvp_job_duration_seconds", []string{"kind", "outcome"},
+		mvpmetrics.Help("Duration of persistent job runs"))
+
+	ephemeralJobsQueuedMetric = mvpmetrics.NewGauge("mvp_ephemeral_jobs_queued", nil,
+		mvpmetrics.Help("Number of ephemeral jobs waiting in the queue"))
+	ephemeralJobsInFlightMetric = mvpmetrics.NewGauge("mvp_ephemeral_jobs_in_flight", []string{"kind"},
+		mvpmetrics.Help("Number of ephemeral jobs queued or running by kind"))
+	ephemeralJobsOverflowMetric = mvpmetrics.NewCounter("mvp_ephemeral_jobs_overflow_total", []string{"kind", "outcome"},
+		mvpmetrics.Help("Number of ephemeral jobs that found the queue full by kind and outcome (queued, dropped, rejected, spilled, lost)"))
+	ephemeralQueueCapacityMetric = mvpmetrics.NewGauge("mvp_ephemeral_jobs_queue_capacity", nil,
+		mvpmetrics.Help("Maximum size of the ephemeral job queue (EphemeralQueueMaxSize)"))
+)
+
+var activeJobStatuses = []mvpjobs.Status{
+	mvpjobs.StatusQueued,
+	mvpjobs.StatusBlocked,
+	mvpjobs.StatusRunning,
+	mvpjobs.StatusRunningPending,
+	mvpjobs.StatusWaiting,
+	mvpjobs.StatusRetrying,
+}
+
+// recordJobCompletion is called by markJobCompleted after the job's new
+// status has been determined; dur is negative for jobs that crashed.
+func recordJobCompletion(j *mvpjobs.Job, outcome string, dur time.Duration) {
+	jobsCompletedMetric.Inc(j.Kind, outcome)
+	if dur >= 0 {
+		jobDurationMetric.ObserveDuration(dur, j.Kind, outcome)
+	}
+}
+
+func jobOutcome(j *mvpjobs.Job, jobErr error) string {
+	switch {
+	case jobErr == nil:
+		return "succeeded"
+	case j.Status == mvpjobs.StatusFailed:
+		return "failed"
+	default:
+		return "retrying"
+	}
+}
+
+func (app *App) collectJobMetricsContinuously(ctx context.Context) {
+	rc := NewRC(ctx, app, "jobs:metrics")
+	defer rc.Close()
+
+	ticker := time.NewTicker(jobMetricsInterval)
+	defer ticker.Stop()
+	for {
+		app.collectJobMetrics(rc)
+		select {
+		case <-ticker.C:
+			break
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// collectJobMetrics updates queue depth gauges. Only non-finished jobs are
+// counted, finished ones are reflected by mvp_jobs_completed_total.
+func (app *App) collectJobMetrics(rc *RC) {
+	counts := make(map[mvpjobs.KindStatus]int64)
+	rc.MustRead(func() {
+		for c := rc.DBTx().IndexScan(activeJobsByKindStatus, edb.FullScan()); c.Next(); {
+			counts[c.IndexKey().(mvpjobs.KindStatus)]++
+		}
+	})
+
+	for _, kind := range app.JobSchema.Kinds() {
+		if !kind.IsPersistent() {
+			continue
+		}
+		for _, status := range activeJobStatuses {
+			ks := mvpjobs.KindStatus{Kind: kind.Name, Status: status}
+			jobsQueuedMetric.Set(counts[ks], kind.Name, status.String())
+			delete(counts, ks)
+		}
+	}
+	for ks, n := range counts { // unknown kinds
+		jobsQueuedMetric.Set(n, ks.Kind, ks.Status.String())
+	}
+}
+
+func (app *App) updateEphemeralJobMetrics() {
+	ephemeralJobsQueuedMetric.Set(int64(app.ephemeralJobQueue.len()))
+}
@@ -0,0 +1,44 @@
+package mvp_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/andreyvit/mvp"
+	"github.com/andreyvit/mvp/mvpjobs"
+	"github.com/andreyvit/mvp/mvpmetrics"
+)
+
+func TestJobMetrics(t *testing.T) {
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = scm.Define("Metered", func(rc *mvp.RC, in *testJobParams) error {
+			if in.Value > 0 {
+				return errors.New("boom")
+			}
+			return nil
+		}, mvpjobs.Idempotent)
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "a"})
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "b"})
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "c", Value: 1})
+	})
+	h.RunDue()
+
+	var buf bytes.Buffer
+	var mw mvpmetrics.Writer
+	mw.Reset(&buf)
+	mvpmetrics.DefaultRegistry.WriteMetricsTo(&mw)
+	mw.Flush()
+	for _, e := range []string{
+		`mvp_jobs_completed_total{kind="Metered",outcome="succeeded"} 2`,
+		`mvp_jobs_completed_total{kind="Metered",outcome="failed"} 1`,
+	} {
+		if !strings.Contains(buf.String(), e+"\n") {
+			t.Errorf("** metrics do not contain %s", e)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package mvp
+
+import (
+	"time"
+
+	"github.com/andreyvit/edb"
+	"github.com/andreyvit/mvp/flogger"
+	"github.com/andreyvit/mvp/mvpjobs"
+)
+
+const pruneJobsBatchSize = 1000
+
+// removedJobKindRetention is how long to keep finished jobs of kinds that are
+// no longer defined, named or not, in case the kind comes back with a rollback.
+const removedJobKindRetention = 7 * 24 * time.Hour
+
+var prunableJobStatuses = []mvpjobs.Status{
+	mvpjobs.StatusDone,
+	mvpjobs.StatusFailed,
+	mvpjobs.StatusFailedSkipped,
+	mvpjobs.StatusCancelled,
+}
+
+// pruneJobs deletes finished anonymous jobs that have outlived their kind's
+// retention period, and all finished jobs of removed kinds after
+// removedJobKindRetention, one batch per write transaction.
+func pruneJobs(rc *RC, in *mvpjobs.NoParams) error {
+	app := rc.App()
+	pruned := make(map[string]int)
+	for _, kindName := range storedJobKindNames(rc) {
+		kind := app.JobSchema.KindByName(kindName)
+		if kind == nil {
+			cutoff := rc.Now().Add(-removedJobKindRetention)
+			err := pruneJobBatches(rc, pruned, kindName+":removed", func() []*mvpjobs.Job {
+				var jobs []*mvpjobs.Job
+				for c := edb.ExactIndexScan[mvpjobs.Job](rc, jobsByKind, kindName); len(jobs) < pruneJobsBatchSize && c.Next(); {
+					if j := c.Row(); j.Status.IsTerminal() && j.FinishKey().FinishTime.Before(cutoff) {
+						jobs = append(jobs, j)
+					}
+				}
+				return jobs
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		for _, status := range prunableJobStatuses {
+			retention := kind.Retention(status)
+			if retention <= 0 {
+				continue
+			}
+			lower := mvpjobs.FinishKey{Kind: kind.Name, Status: status, FinishTime: time.Unix(0, 0)}
+			upper := mvpjobs.FinishKey{Kind: kind.Name, Status: status, FinishTime: rc.Now().Add(-retention)}
+			err := pruneJobBatches(rc, pruned, kind.Name+":"+status.String(), func() []*mvpjobs.Job {
+				return edb.AllLimited(edb.RangeIndexScan[mvpjobs.Job](rc, finishedJobsByKindStatusTime, lower, upper, true, false), pruneJobsBatchSize)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if len(pruned) > 0 {
+		flogger.Log(rc, "pruned finished jobs: %v", pruned)
+	}
+	return nil
+}
+
+// pruneJobBatches deletes the jobs returned by find, calling it in a new write
+// transaction until it returns less than pruneJobsBatchSize jobs.
+func pruneJobBatches(rc *RC, pruned map[string]int, key string, find func() []*mvpjobs.Job) error {
+	app := rc.App()
+	for {
+		if err := rc.Err(); err != nil {
+			return err
+		}
+		var n int
+		rc.MustWrite(func() {
+			jobs := find()
+			for _, j := range jobs {
+				runHooksFwd2(app.Hooks.archiveJob, rc, j)
+				edb.DeleteRow(rc, j)
+			}
+			n = len(jobs)
+		})
+		if n > 0 {
+			pruned[key] += n
+		}
+		if n < pruneJobsBatchSize {
+			return nil
+		}
+	}
+}
+
+// storedJobKindNames returns the kinds of all jobs in the database, including
+// the ones that are no longer defined.
+func storedJobKindNames(rc *RC) []string {
+	var names []string
+	rc.MustRead(func() {
+		for {
+			scan := edb.FullScan()
+			if len(names) > 0 {
+				scan = edb.LowerBoundScan(names[len(names)-1], false)
+			}
+			j := edb.First(edb.IndexScan[mvpjobs.Job](rc, jobsByKind, scan))
+			if j == nil {
+				return
+			}
+			names = append(names, j.Kind)
+		}
+	})
+	return names
+}
@@ -0,0 +1,98 @@
+package mvp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andreyvit/edb"
+	"github.com/andreyvit/mvp"
+	"github.com/andreyvit/mvp/mvpjobs"
+	"github.com/andreyvit/mvp/mvpjobstest"
+)
+
+func TestPruneJobs(t *testing.T) {
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = scm.Define("Ping", func(rc *mvp.RC, in *testJobParams) error {
+			return nil
+		}, mvpjobs.Idempotent, mvpjobs.WithDoneRetention(90*time.Minute))
+	})
+	pruneKind := h.App.JobSchema.KindByName("PruneJobs")
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, kind, &testJobParams{})
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "named"})
+		h.App.EnqueueAt(rc, pruneKind, &mvpjobs.NoParams{}, testStartTime.Add(time.Hour))
+	})
+	h.RunDue()
+	if n := countJobs(h, kind); n != 2 {
+		t.Fatalf("** %d jobs before pruning, wanted 2", n)
+	}
+
+	h.Advance(time.Hour)
+	if n := countJobs(h, kind); n != 2 {
+		t.Errorf("** %d jobs after 1h, wanted 2", n)
+	}
+	h.Advance(time.Hour)
+	if n := countJobs(h, kind); n != 1 {
+		t.Errorf("** %d jobs after 2h, wanted 1 (only the named one)", n)
+	}
+	h.ExpectStatus(kind, "named", mvpjobs.StatusDone, 1)
+}
+
+func TestPruneJobs_removedKind(t *testing.T) {
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = scm.Define("Ping", func(rc *mvp.RC, in *testJobParams) error {
+			return nil
+		}, mvpjobs.Idempotent)
+	})
+	gone := &mvpjobs.Kind{Name: "Gone"}
+	mustWrite(h, func(rc *mvp.RC) {
+		for _, j := range []*mvpjobs.Job{
+			{Name: "old", Status: mvpjobs.StatusDone, FinishTime: testStartTime.Add(-8 * 24 * time.Hour)},
+			{Status: mvpjobs.StatusFailed, FinishTime: testStartTime.Add(-8 * 24 * time.Hour)},
+			{Name: "recent", Status: mvpjobs.StatusDone, FinishTime: testStartTime.Add(-24 * time.Hour)},
+			{Name: "queued", Status: mvpjobs.StatusQueued, EnqueueTime: testStartTime.Add(-8 * 24 * time.Hour)},
+		} {
+			j.ID = h.App.NewID()
+			j.Kind = gone.Name
+			edb.Put(rc, j)
+		}
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "named"})
+		h.App.Enqueue(rc, h.App.JobSchema.KindByName("PruneJobs"), &mvpjobs.NoParams{})
+	})
+	h.RunDue()
+
+	if n := countJobs(h, gone); n != 2 {
+		t.Errorf("** %d jobs of the removed kind left, wanted 2", n)
+	}
+	if h.Job(gone, "old") != nil {
+		t.Errorf("** old finished job of the removed kind not pruned")
+	}
+	h.ExpectStatus(gone, "recent", mvpjobs.StatusDone, 0)
+	h.ExpectStatus(gone, "queued", mvpjobs.StatusQueued, 0)
+	h.ExpectStatus(kind, "named", mvpjobs.StatusDone, 1)
+}
+
+func TestPruneJobs_noPersistentJobs(t *testing.T) {
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		scm.Define("Ping", nil, mvpjobs.Idempotent, mvpjobs.Ephemeral)
+	})
+	if h.App.JobSchema.KindByName("PruneJobs") != nil {
+		t.Errorf("** PruneJobs registered in an app without persistent jobs")
+	}
+}
+
+func countJobs(h *mvpjobstest.Harness, kind *mvpjobs.Kind) int {
+	rc := mvp.NewRC(h.Ctx, h.App, "test")
+	defer rc.Close()
+	var n int
+	rc.MustRead(func() {
+		for c := edb.FullTableScan[mvpjobs.Job](rc); c.Next(); {
+			if c.Row().Kind == kind.Name {
+				n++
+			}
+		}
+	})
+	return n
+}
@@ -0,0 +1,55 @@
+package mvp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/andreyvit/mvp/mvpjobs"
+	"golang.org/x/time/rate"
+)
+
+// jobRateLimiter returns the token bucket for the kind's WithRateLimit option,
+// shared by all kinds of the set for PerSet limits.
+func (app *App) jobRateLimiter(kind *mvpjobs.Kind) *rate.Limiter {
+	rl := kind.RateLimit
+	if rl == nil {
+		return nil
+	}
+	if !rl.PerSet {
+		return rate.NewLimiter(rl.PerSec, rl.Burst)
+	}
+
+	if app.jobSetLimiters == nil {
+		app.jobSetLimiters = make(map[string]*rate.Limiter)
+	}
+	if limiter := app.jobSetLimiters[kind.Set]; limiter != nil {
+		if limiter.Limit() != rl.PerSec || limiter.Burst() != rl.Burst {
+			panic(fmt.Errorf("%s: rate limit %v/%d of job set %q conflicts with another kind's %v/%d", kind.Name, rl.PerSec, rl.Burst, kind.Set, limiter.Limit(), limiter.Burst()))
+		}
+		return limiter
+	}
+	limiter := rate.NewLimiter(rl.PerSec, rl.Burst)
+	app.jobSetLimiters[kind.Set] = limiter
+	return limiter
+}
+
+// allowJobByRateLimit takes a token from the kind's rate limiter, if any.
+// Called by dequeuePendingJob as the last check before picking a job, so
+// that a token is only spent when the job actually starts. When there's no
+// token, returns the time the next one becomes available.
+func (app *App) allowJobByRateLimit(rc *RC, kind *mvpjobs.Kind) (bool, time.Time) {
+	ji := app.jobsByKind[kind]
+	if ji == nil || ji.RateLimiter == nil {
+		return true, time.Time{}
+	}
+	now := rc.Now()
+	if ji.RateLimiter.AllowN(now, 1) {
+		return true, time.Time{}
+	}
+	limit := ji.RateLimiter.Limit()
+	if limit <= 0 {
+		return false, time.Time{}
+	}
+	missing := 1 - ji.RateLimiter.TokensAt(now)
+	return false, now.Add(time.Duration(missing / float64(limit) * float64(time.Second)))
+}
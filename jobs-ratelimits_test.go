@@ -0,0 +1,53 @@
+package mvp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andreyvit/mvp"
+	"github.com/andreyvit/mvp/mvpjobs"
+)
+
+func TestJobRateLimit(t *testing.T) {
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = scm.Define("Call", func(rc *mvp.RC, in *testJobParams) error {
+			return nil
+		}, mvpjobs.Idempotent, mvpjobs.WithRateLimit{PerSec: 2, Burst: 2})
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		for _, name := range []string{"a", "b", "c", "d", "e"} {
+			h.App.Enqueue(rc, kind, &testJobParams{Name: name})
+		}
+	})
+
+	// a worker sleeps until the next token instead of polling
+	if n, d := h.App.RunJobsAsWorker(h.Ctx); n != 2 || d != 500*time.Millisecond {
+		t.Errorf("** RunJobsAsWorker = %d, %v, wanted 2, 500ms", n, d)
+	}
+	if n := h.Advance(500 * time.Millisecond); n != 1 {
+		t.Errorf("** ran %d jobs after 500ms, wanted 1", n)
+	}
+	if n := h.Advance(time.Second); n != 2 {
+		t.Errorf("** ran %d jobs after 1.5s, wanted 2", n)
+	}
+	h.ExpectStatus(kind, "e", mvpjobs.StatusDone, 1)
+}
+
+func TestJobWorkerIdleDelay(t *testing.T) {
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = scm.Define("Later", func(rc *mvp.RC, in *testJobParams) error {
+			return nil
+		}, mvpjobs.Idempotent)
+	})
+	if _, d := h.App.RunJobsAsWorker(h.Ctx); d != 5*time.Second {
+		t.Errorf("** idle delay with no jobs = %v, wanted 5s", d)
+	}
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.EnqueueAfter(rc, kind, &testJobParams{Name: "a"}, 2*time.Second)
+	})
+	if _, d := h.App.RunJobsAsWorker(h.Ctx); d != 2*time.Second {
+		t.Errorf("** idle delay with a job due in 2s = %v, wanted 2s", d)
+	}
+}
@@ -0,0 +1,118 @@
+package mvp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/andreyvit/edb"
+	"github.com/andreyvit/mvp/flogger"
+	"github.com/andreyvit/mvp/mvpjobs"
+)
+
+var jobHeartbeatInterval = time.Minute // var for tests
+
+const jobHeartbeatTimeout = 5 * time.Minute
+
+// startJobHeartbeat periodically records that the given jobs, started together,
+// are still running, and cancels those that another process has asked to
+// cancel (see CancelJob). Heartbeats stop once the jobs exceed their kind's timeout,
+// so that a handler ignoring the cancellation of its context gets reaped by
+// sweepStuckJobs.
+func (app *App) startJobHeartbeat(ctx context.Context, kind *mvpjobs.Kind, jobs ...*mvpjobs.Job) (stop func()) {
+	stopc := make(chan struct{})
+	j := jobs[0]
+	go func() {
+		rc := NewRC(ctx, app, fmt.Sprintf("jobs:hb:%s:%v", kind.Name, j.ID))
+		defer rc.Close()
+
+		ticker := time.NewTicker(jobHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				rc.RefreshNowTime()
+				if kind.Timeout > 0 && rc.Now().Sub(j.StartTime) > kind.Timeout {
+					return
+				}
+				rc.MustWrite(func() {
+					for _, j := range jobs {
+						cur := edb.Reload(rc, j)
+						if cur != nil && cur.Status.IsRunning() && cur.Attempt == j.Attempt {
+							cur.HeartbeatTime = rc.Now()
+							edb.Put(rc, cur)
+							if cur.CancelRequested {
+								rc.cancelJobAfterCommit(cur.ID)
+							}
+						}
+					}
+				})
+			case <-stopc:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return func() { close(stopc) }
+}
+
+func (app *App) sweepStuckJobsContinuously(ctx context.Context) {
+	rc := NewRC(ctx, app, "jobs:sweeper")
+	defer rc.Close()
+
+	ticker := time.NewTicker(jobHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			rc.RefreshNowTime()
+			app.sweepStuckJobs(rc)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sweepStuckJobs fails running jobs that haven't sent a heartbeat within
+// jobHeartbeatTimeout, subjecting them to the usual backoff rules. Jobs of
+// kinds unknown to this process are left for the processes that know them.
+// Only opens a write transaction once a stuck job is found.
+func (app *App) sweepStuckJobs(rc *RC) int {
+	deadline := rc.Now().Add(-jobHeartbeatTimeout)
+	isStuck := func(j *mvpjobs.Job) bool {
+		return j.Status.IsRunning() && j.LastAliveTime().Before(deadline) && app.JobSchema.KindByName(j.Kind) != nil
+	}
+
+	var candidates []*mvpjobs.Job
+	rc.MustRead(func() {
+		for c := edb.FullIndexScan[mvpjobs.Job](rc, runningJobsByStartTime); c.Next(); {
+			if j := c.Row(); isStuck(j) {
+				candidates = append(candidates, j)
+			}
+		}
+	})
+	if len(candidates) == 0 {
+		return 0
+	}
+
+	var stuck []*mvpjobs.Job
+	rc.MustWrite(func() {
+		stuck = stuck[:0]
+		for _, j := range candidates {
+			cur := edb.Reload(rc, j)
+			if cur == nil || cur.Attempt != j.Attempt || !isStuck(cur) {
+				continue // finished or sent a heartbeat in the meantime
+			}
+			app.markJobCompleted(rc, app.JobSchema.KindByName(cur.Kind), cur, errJobStuck, rc.Now().Sub(cur.StartTime))
+			stuck = append(stuck, cur)
+		}
+	})
+	for _, j := range stuck {
+		app.runningJobs.cancel(j.ID, errJobStuck)
+	}
+	if len(stuck) > 0 {
+		flogger.Log(rc, "reaped %d stuck jobs", len(stuck))
+	}
+	return len(stuck)
+}
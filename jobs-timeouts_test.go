@@ -0,0 +1,64 @@
+package mvp_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/mvp"
+	"github.com/andreyvit/mvp/mvpjobs"
+	mvpm "github.com/andreyvit/mvp/mvpmodel"
+)
+
+func TestJobTimeout(t *testing.T) {
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = scm.Define("Slow", func(rc *mvp.RC, in *testJobParams) error {
+			<-rc.Done()
+			return rc.Err()
+		}, mvpjobs.Idempotent, mvpjobs.WithTimeout(20*time.Millisecond), mvpm.Manual)
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "a"})
+	})
+	h.RunDue()
+	h.ExpectStatus(kind, "a", mvpjobs.StatusFailed, 1)
+	if j := h.Job(kind, "a"); !strings.HasPrefix(j.LastErr, "timed out after 20ms") {
+		t.Errorf("** LastErr = %q, wanted timeout", j.LastErr)
+	}
+}
+
+func TestSweepStuckJobs(t *testing.T) {
+	started := make(chan *mvp.RC, 1)
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = blockingJob(scm, started)
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "a"})
+	})
+
+	done := make(chan int)
+	go func() { done <- h.RunDue() }()
+	<-started
+
+	h.Clock.Advance(4 * time.Minute)
+	if n := h.App.SweepStuckJobs(h.Ctx); n != 0 {
+		t.Errorf("** reaped %d jobs before the heartbeat timeout, wanted 0", n)
+	}
+	h.ExpectStatus(kind, "a", mvpjobs.StatusRunning, 1)
+
+	h.Clock.Advance(2 * time.Minute)
+	if n := h.App.SweepStuckJobs(h.Ctx); n != 1 {
+		t.Errorf("** reaped %d jobs after the heartbeat timeout, wanted 1", n)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("** stuck handler not cancelled")
+	}
+	h.ExpectStatus(kind, "a", mvpjobs.StatusFailed, 1)
+	if j := h.Job(kind, "a"); j.LastErr != "stuck: no heartbeat" {
+		t.Errorf("** LastErr = %q, wanted stuck", j.LastErr)
+	}
+}
@@ -6,6 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
+	"reflect"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/andreyvit/edb"
@@ -13,10 +17,14 @@ import (
 	"github.com/andreyvit/mvp/flogger"
 	"github.com/andreyvit/mvp/mvpjobs"
 	"github.com/andreyvit/mvp/mvprpc"
+	"golang.org/x/time/rate"
 )
 
 var (
-	errJobCrashed = errors.New("process crashed")
+	errJobCrashed   = errors.New("process crashed")
+	errJobCancelled = errors.New("cancelled")
+	errJobTimedOut  = errors.New("timed out")
+	errJobStuck     = errors.New("stuck: no heartbeat")
 )
 
 type JobImpl struct {
@@ -24,6 +32,68 @@ type JobImpl struct {
 	Enabled        bool
 	Kind           *mvpjobs.Kind
 	RepeatInterval time.Duration
+	Schedule       *mvpjobs.Schedule
+	RateLimiter    *rate.Limiter
+
+	callBatch func(rc *RC, ins []mvpjobs.Params) ([]error, error)
+}
+
+// NextRepeatTime returns the time of the next run of a cron job after the given
+// time, or zero time if the job doesn't repeat on its own.
+func (ji *JobImpl) NextRepeatTime(after time.Time) time.Time {
+	if ji.Schedule != nil {
+		return ji.Schedule.Next(after)
+	} else if ji.RepeatInterval > 0 {
+		return after.Add(ji.RepeatInterval)
+	} else {
+		return time.Time{}
+	}
+}
+
+// runningJobs tracks the contexts of persistent jobs executing in this process,
+// so that they can be cancelled.
+type runningJobs struct {
+	mut sync.Mutex
+	m   map[mvpjobs.JobID]context.CancelCauseFunc
+}
+
+func (rj *runningJobs) start(ctx context.Context, jid mvpjobs.JobID, timeout time.Duration) context.Context {
+	ctx, cancel := context.WithCancelCause(ctx)
+	if timeout > 0 {
+		var stop context.CancelFunc
+		ctx, stop = context.WithTimeoutCause(ctx, timeout, errJobTimedOut)
+		cancelCause := cancel
+		cancel = func(cause error) {
+			cancelCause(cause)
+			stop()
+		}
+	}
+	rj.mut.Lock()
+	defer rj.mut.Unlock()
+	if rj.m == nil {
+		rj.m = make(map[mvpjobs.JobID]context.CancelCauseFunc)
+	}
+	rj.m[jid] = cancel
+	return ctx
+}
+
+func (rj *runningJobs) finish(jid mvpjobs.JobID) {
+	rj.mut.Lock()
+	defer rj.mut.Unlock()
+	if cancel := rj.m[jid]; cancel != nil {
+		cancel(nil)
+		delete(rj.m, jid)
+	}
+}
+
+func (rj *runningJobs) cancel(jid mvpjobs.JobID, cause error) bool {
+	rj.mut.Lock()
+	defer rj.mut.Unlock()
+	if cancel := rj.m[jid]; cancel != nil {
+		cancel(cause)
+		return true
+	}
+	return false
 }
 
 type jobImpl struct {
@@ -31,69 +101,181 @@ type jobImpl struct {
 	opts []any
 }
 
-func (app *App) Enqueue(rc RCish, kind *mvpjobs.Kind, in mvpjobs.Params) *mvpjobs.Job {
+func (app *App) Enqueue(rc RCish, kind *mvpjobs.Kind, in mvpjobs.Params, opts ...any) *mvpjobs.Job {
+	var eo mvpjobs.EnqueueOptions
+	eo.Apply(opts...)
+	app.validateJobDeps(eo.WaitFor)
+	return app.enqueue(rc, kind, in, &eo)
+}
+
+func (app *App) enqueue(rc RCish, kind *mvpjobs.Kind, in mvpjobs.Params, eo *mvpjobs.EnqueueOptions) *mvpjobs.Job {
 	name := in.JobName()
 	if j := app.Job(rc, kind, name); j != nil {
-		app.Reenqueue(rc, kind, j, in, false)
+		app.reenqueue(rc, kind, j, in, false, eo)
+		if eo.Priority != nil && !j.Status.IsTerminal() && j.Priority != *eo.Priority {
+			j.Priority = *eo.Priority
+			edb.Put(rc, j)
+		}
+		app.addJobDeps(rc, j, eo.WaitFor)
 		return j
 	}
 
 	j := &mvpjobs.Job{
-		ID:          app.NewID(),
-		Kind:        kind.Name,
-		Name:        in.JobName(),
-		RawParams:   mvpjobs.EncodeParams(in),
-		Status:      mvpjobs.StatusQueued,
-		NextRunTime: rc.Now(),
-		EnqueueTime: rc.Now(),
+		ID:            app.NewID(),
+		Kind:          kind.Name,
+		Name:          in.JobName(),
+		RawParams:     mvpjobs.EncodeParams(in),
+		ParamsVersion: kind.ParamsVersion,
+		Status:        mvpjobs.StatusQueued,
+		NextRunTime:   eo.RunTimeOr(rc.Now()),
+		Priority:      eo.PriorityOr(kind.Priority),
+		EnqueueTime:   rc.Now(),
+		Deps:          eo.WaitFor,
+		Partition:     kind.Partition(in),
+	}
+	j.PartitionSeq = uint64(j.ID)
+	if len(j.Deps) > 0 {
+		app.updateBlockedJob(rc, j)
 	}
 	edb.Put(rc, j)
+	app.unblockDependentJobs(rc, j)
 	return j
 }
 
+// EnqueueAt is like Enqueue, but runs the job no earlier than the given time.
+// If a pending named job already exists, its run time is moved according
+// to the mvpjobs.RunTimeConflict option, keeping the earlier one by default.
+func (app *App) EnqueueAt(rc RCish, kind *mvpjobs.Kind, in mvpjobs.Params, when time.Time, opts ...any) *mvpjobs.Job {
+	return app.Enqueue(rc, kind, in, append(opts, mvpjobs.WithRunTime(when))...)
+}
+
+// EnqueueAfter is like EnqueueAt, but takes a delay relative to rc.Now().
+func (app *App) EnqueueAfter(rc RCish, kind *mvpjobs.Kind, in mvpjobs.Params, delay time.Duration, opts ...any) *mvpjobs.Job {
+	return app.EnqueueAt(rc, kind, in, rc.Now().Add(delay), opts...)
+}
+
 func (app *App) Reenqueue(rc RCish, kind *mvpjobs.Kind, j *mvpjobs.Job, in mvpjobs.Params, force bool) {
+	app.reenqueue(rc, kind, j, in, force, &mvpjobs.EnqueueOptions{})
+}
+
+// retryJob re-enqueues a job on an operator's request, restarting its backoff
+// so that it gets the full number of retries again.
+func (app *App) retryJob(rc RCish, kind *mvpjobs.Kind, j *mvpjobs.Job) {
+	j.ConsecFailures = 0
+	app.Reenqueue(rc, kind, j, nil, true)
+	edb.Put(rc, j)
+}
+
+func (app *App) reenqueue(rc RCish, kind *mvpjobs.Kind, j *mvpjobs.Job, in mvpjobs.Params, force bool, eo *mvpjobs.EnqueueOptions) {
+	runTime := eo.RunTimeOr(rc.Now())
 	if kind.Behavior == mvpjobs.Repeatable {
 		if j.Status == mvpjobs.StatusRunning {
 			j.Status = mvpjobs.StatusRunningPending
-			if in != nil {
-				j.RawParams = mvpjobs.EncodeParams(in)
-			}
-			j.NextRunTime = rc.Now()
+			setJobParams(kind, j, in)
+			j.NextRunTime = runTime
 			edb.Put(rc, j)
 		} else if j.Status.IsTerminal() {
 			j.Status = mvpjobs.StatusQueued
-			if in != nil {
-				j.RawParams = mvpjobs.EncodeParams(in)
-			}
-			j.NextRunTime = rc.Now()
+			setJobParams(kind, j, in)
+			j.NextRunTime = runTime
+			j.Priority = eo.PriorityOr(kind.Priority)
 			j.EnqueueTime = rc.Now()
+			j.PartitionSeq = uint64(app.NewID())
+			j.Deps = nil
 			edb.Put(rc, j)
-		} else if j.Status.IsPending() && j.NextRunTime.After(rc.Now()) {
-			j.NextRunTime = rc.Now()
-			if in != nil {
-				j.RawParams = mvpjobs.EncodeParams(in)
-			}
+		} else if j.Status.IsPending() && eo.RunTimeConflict.ShouldReplace(j.NextRunTime, runTime) {
+			j.NextRunTime = runTime
+			setJobParams(kind, j, in)
 			edb.Put(rc, j)
 		}
 	} else if force {
 		if j.Status.IsTerminal() {
 			j.Status = mvpjobs.StatusQueued
-			if in != nil {
-				j.RawParams = mvpjobs.EncodeParams(in)
-			}
-			j.NextRunTime = rc.Now()
+			setJobParams(kind, j, in)
+			j.NextRunTime = runTime
+			j.Priority = eo.PriorityOr(kind.Priority)
 			j.EnqueueTime = rc.Now()
+			j.PartitionSeq = uint64(app.NewID())
+			j.Deps = nil
 			edb.Put(rc, j)
-		} else if j.Status.IsPending() && j.NextRunTime.After(rc.Now()) {
-			j.NextRunTime = rc.Now()
-			if in != nil {
-				j.RawParams = mvpjobs.EncodeParams(in)
-			}
+		} else if j.Status.IsPending() && eo.RunTimeConflict.ShouldReplace(j.NextRunTime, runTime) {
+			j.NextRunTime = runTime
+			setJobParams(kind, j, in)
 			edb.Put(rc, j)
 		}
+	} else if !eo.RunTime.IsZero() {
+		// don't interfere with retry backoff, but honor explicit scheduling
+		if j.Status == mvpjobs.StatusQueued && eo.RunTimeConflict.ShouldReplace(j.NextRunTime, runTime) {
+			j.NextRunTime = runTime
+			edb.Put(rc, j)
+		}
+	}
+}
+
+// setJobParams updates the params of a job along with its partition key;
+// nil params keep the existing ones.
+func setJobParams(kind *mvpjobs.Kind, j *mvpjobs.Job, in mvpjobs.Params) {
+	if in != nil {
+		j.RawParams = mvpjobs.EncodeParams(in)
+		j.ParamsVersion = kind.ParamsVersion
+		j.Partition = kind.Partition(in)
 	}
 }
 
+// CancelJob cancels a queued or running job, returning nil if there's no such
+// job. A queued job is marked as cancelled immediately. A running job sees
+// the cancellation via its RC context once the transaction commits (or, when
+// running in another process, at its next heartbeat), and is marked as
+// cancelled when its handler returns. Must be called within a write
+// transaction.
+func (app *App) CancelJob(rc RCish, kind *mvpjobs.Kind, name string) *mvpjobs.Job {
+	j := app.Job(rc, kind, name)
+	if j != nil {
+		app.cancelJob(rc, j)
+	}
+	return j
+}
+
+func (app *App) cancelJob(rc RCish, j *mvpjobs.Job) {
+	if j.Status.IsTerminal() {
+		return
+	}
+	if j.Status.IsRunning() {
+		j.CancelRequested = true
+		edb.Put(rc, j)
+		rc.BaseRC().cancelJobAfterCommit(j.ID)
+	} else {
+		app.markJobCancelled(rc, j)
+	}
+}
+
+// cancelJobAfterCommit cancels the context of a job running in this process
+// once the current write transaction commits, so that the handler doesn't
+// observe the cancellation before CancelRequested is saved.
+func (rc *RC) cancelJobAfterCommit(jid mvpjobs.JobID) {
+	rc.jobsToCancel = append(rc.jobsToCancel, jid)
+}
+
+func (rc *RC) applyDelayedJobCancellation(committed bool) {
+	jids := rc.jobsToCancel
+	rc.jobsToCancel = nil
+	if committed {
+		for _, jid := range jids {
+			rc.app.runningJobs.cancel(jid, errJobCancelled)
+		}
+	}
+}
+
+func (app *App) markJobCancelled(rc RCish, j *mvpjobs.Job) {
+	j.Status = mvpjobs.StatusCancelled
+	j.NextRunTime = time.Time{}
+	j.CancelRequested = false
+	j.LastErr = errJobCancelled.Error()
+	j.FinishTime = rc.Now()
+	edb.Put(rc, j)
+	app.unblockDependentJobs(rc, j)
+}
+
 func (app *App) failRunningJobs(ctx context.Context) {
 	rc := NewRC(ctx, app, "jobs")
 	defer rc.Close()
@@ -122,34 +304,117 @@ func (app *App) failRunningJobs(ctx context.Context) {
 	}
 }
 
+func (app *App) seedCronJobs(ctx context.Context) {
+	rc := NewRC(ctx, app, "jobs")
+	defer rc.Close()
+
+	var seeded []string
+	rc.MustWrite(func() {
+		now := rc.Now()
+		for kind, jobImpl := range app.jobsByKind {
+			if !kind.IsCron() || !jobImpl.Enabled {
+				continue
+			}
+			// latest is the time of the next run under the current definition;
+			// a new interval job runs right away, but an existing one keeps
+			// its planned time unless the interval has been shortened
+			latest := jobImpl.NextRepeatTime(now)
+			if latest.IsZero() {
+				continue
+			}
+
+			j := app.Job(rc, kind, "")
+			if j == nil {
+				first := latest
+				if jobImpl.Schedule == nil {
+					first = now
+				}
+				edb.Put(rc, &mvpjobs.Job{
+					ID:            app.NewID(),
+					Kind:          kind.Name,
+					RawParams:     mvpjobs.EncodeParams(nil),
+					ParamsVersion: kind.ParamsVersion,
+					Status:        mvpjobs.StatusQueued,
+					NextRunTime:   first,
+					Priority:      kind.Priority,
+					EnqueueTime:   now,
+				})
+				seeded = append(seeded, kind.Name)
+			} else if j.Status.IsPending() && j.NextRunTime.After(latest) {
+				// schedule has changed to an earlier time
+				j.NextRunTime = latest
+				edb.Put(rc, j)
+			}
+		}
+	})
+	if len(seeded) > 0 {
+		flogger.Log(rc, "seeded cron jobs: %v", seeded)
+	}
+}
+
+// allJobSets makes dequeuePendingJob pick jobs of any set.
+const allJobSets = "*"
+
+const (
+	jobWorkerPollInterval = 5 * time.Second
+	minJobWorkerIdleDelay = 10 * time.Millisecond
+)
+
+// StartJobWorkers starts count workers for the kinds in the default set, plus
+// the number of workers configured in Settings.WorkerSets for each named set.
 func (app *App) StartJobWorkers(ctx context.Context, count int, quitf func(err error)) {
 	app.failRunningJobs(ctx)
+	app.seedCronJobs(ctx)
+	go app.sweepStuckJobsContinuously(ctx)
+	go app.collectJobMetricsContinuously(ctx)
+
+	counts := make(map[string]int, len(app.Settings.WorkerSets)+1)
+	for set, n := range app.Settings.WorkerSets {
+		counts[set] = n
+	}
+	counts[""] = count
 
-	if count == 0 {
+	var total int
+	for _, n := range counts {
+		total += n
+	}
+	if total == 0 {
 		return
 	}
+	for _, kind := range app.JobSchema.Kinds() {
+		if kind.IsPersistent() && counts[kind.Set] == 0 {
+			log.Printf("** WARNING: no workers for job set %q, %s jobs won't run", kind.Set, kind.Name)
+		}
+	}
 
-	donec := make(chan struct{}, count)
-	for i := 1; i <= count; i++ {
-		go app.runJobsContinuously(ctx, i, count, donec)
+	donec := make(chan struct{}, total)
+	for set, n := range counts {
+		for i := 1; i <= n; i++ {
+			go app.runJobsContinuously(ctx, set, i, n, donec)
+		}
 	}
 	go func() {
-		for i := 1; i <= count; i++ {
+		for i := 1; i <= total; i++ {
 			<-donec
 		}
 		quitf(nil)
 	}()
 }
 
-func (app *App) runJobsContinuously(ctx context.Context, workerIdx, workerCount int, donec chan<- struct{}) {
+func (app *App) runJobsContinuously(ctx context.Context, set string, workerIdx, workerCount int, donec chan<- struct{}) {
 	defer sendSignal(donec)
-	rc := NewRC(ctx, app, fmt.Sprintf("jobs:w%d", workerIdx))
+	var rc *RC
+	if set == "" {
+		rc = NewRC(ctx, app, fmt.Sprintf("jobs:w%d", workerIdx))
+	} else {
+		rc = NewRC(ctx, app, fmt.Sprintf("jobs:%s:w%d", set, workerIdx))
+	}
 	defer rc.Close()
 	for ctx.Err() == nil {
-		c := app.runPendingJobsOnce(rc, workerIdx, workerCount)
+		c, retryAt := app.runPendingJobsOnce(rc, set, workerIdx, workerCount)
 		if c == 0 {
 			select {
-			case <-time.After(5 * time.Second):
+			case <-time.After(app.jobWorkerIdleDelay(rc, retryAt)):
 				break
 			case <-ctx.Done():
 				return
@@ -158,53 +423,119 @@ func (app *App) runJobsContinuously(ctx context.Context, workerIdx, workerCount
 	}
 }
 
+// jobWorkerIdleDelay returns how long an idle worker sleeps: until the next
+// pending job is due or a rate-limited kind gets its next token (retryAt),
+// whichever comes first, but no longer than jobWorkerPollInterval, so that
+// jobs enqueued by other processes get picked up.
+func (app *App) jobWorkerIdleDelay(rc *RC, retryAt time.Time) time.Duration {
+	now := rc.Now()
+	wake := now.Add(jobWorkerPollInterval)
+	if !retryAt.IsZero() && retryAt.Before(wake) {
+		wake = retryAt
+	}
+	rc.MustRead(func() {
+		if next := app.NextPendingJobTime(rc, now); !next.IsZero() && next.Before(wake) {
+			wake = next
+		}
+	})
+	return max(wake.Sub(now), minJobWorkerIdleDelay)
+}
+
+// RunPendingJobs runs all due jobs of all sets in the calling goroutine,
+// returning the number of jobs executed.
 func (app *App) RunPendingJobs(ctx context.Context) int {
 	rc := NewRC(ctx, app, "jobs")
 	defer rc.Close()
-	return app.runPendingJobsOnce(rc, 0, 0)
+	count, _ := app.runPendingJobsOnce(rc, allJobSets, 0, 0)
+	return count
 }
 
+// RunJob runs a job of a persistent kind in the calling goroutine, bypassing
+// the queue; the job isn't recorded in the database.
 func (app *App) RunJob(ctx context.Context, kind *mvpjobs.Kind, params mvpjobs.Params) error {
-	return app.executeJob(ctx, app.NewID(), kind, params.JobName(), mvpjobs.EncodeParams(params), 1, 0, 0)
+	if !kind.IsPersistent() {
+		return fmt.Errorf("%s is an ephemeral job kind, only persistent ones can be run", kind.Name)
+	}
+	j := &mvpjobs.Job{
+		ID:            app.NewID(),
+		Kind:          kind.Name,
+		Name:          params.JobName(),
+		RawParams:     mvpjobs.EncodeParams(params),
+		ParamsVersion: kind.ParamsVersion,
+		Attempt:       1,
+	}
+	if kind.BatchHandler != nil {
+		return app.executeJobBatch(ctx, kind, []*mvpjobs.Job{j}, 0, 0)[0]
+	}
+	return app.executeJob(ctx, kind, j, 0, 0)
 }
 
-func (app *App) runPendingJobsOnce(rc *RC, workerIdx, workerCount int) int {
+// runPendingJobsOnce runs due jobs until there are none left that can run,
+// returning the number of jobs executed and, if some due jobs have been held
+// back by rate limits, the time the earliest of them can run.
+func (app *App) runPendingJobsOnce(rc *RC, set string, workerIdx, workerCount int) (int, time.Time) {
 	var count int
 	for {
 		rc.RefreshNowTime()
-		if app.runSinglePendingJob(rc, workerIdx, workerCount) {
-			count++
-			// } else ...TODO: cron... { ...
-		} else {
-			break
+		n, retryAt := app.runNextPendingJobs(rc, set, workerIdx, workerCount)
+		if n == 0 {
+			return count, retryAt
 		}
+		count += n
 	}
-	return count
 }
 
-func (app *App) runSinglePendingJob(rc *RC, workerIdx, workerCount int) bool {
-	j := app.dequeuePendingJob(rc)
-	if j == nil {
-		return false
+// runNextPendingJobs runs the next due job, or the next batch of jobs for
+// kinds with a batch handler, returning the number of jobs executed. See
+// dequeuePendingJobs for retryAt.
+func (app *App) runNextPendingJobs(rc *RC, set string, workerIdx, workerCount int) (int, time.Time) {
+	jobs, retryAt := app.dequeuePendingJobs(rc, set)
+	if len(jobs) == 0 {
+		return 0, retryAt
+	}
+	if kind := app.JobSchema.KindByName(jobs[0].Kind); kind != nil && kind.BatchHandler != nil {
+		app.runJobBatch(rc, kind, jobs, workerIdx, workerCount)
+	} else {
+		app.runSingleJob(rc, jobs[0], workerIdx, workerCount)
 	}
+	return len(jobs), retryAt
+}
+
+func (app *App) runSingleJob(rc *RC, j *mvpjobs.Job, workerIdx, workerCount int) {
 	kind := app.JobSchema.KindByName(j.Kind)
 
 	var jobErr error
 	if kind == nil {
 		jobErr = fmt.Errorf("unknown job kind: %q", j.Kind)
 	} else {
-		jobErr = app.executeJob(rc, j.ID, kind, j.Name, j.RawParams, j.Attempt, workerIdx, workerCount)
+		ctx := app.runningJobs.start(rc, j.ID, kind.Timeout)
+		stopHeartbeat := app.startJobHeartbeat(rc, kind, j)
+		jobErr = app.executeJob(ctx, kind, j, workerIdx, workerCount)
+		stopHeartbeat()
+		if jobErr != nil && context.Cause(ctx) == errJobTimedOut {
+			jobErr = fmt.Errorf("%w after %v: %v", errJobTimedOut, kind.Timeout, jobErr)
+		}
+		app.runningJobs.finish(j.ID)
 	}
-	dur := time.Since(j.StartTime)
+	dur := app.Now().Sub(j.StartTime)
 	if jobErr != nil {
 		log.Printf("** WARNING: job failed: %s %v %s: %v", j.Kind, j.ID, j.RawParams, jobErr)
 	}
 
 	rc.MustWrite(func() {
-		j := edb.Reload(rc, j)
-		app.markJobCompleted(rc, kind, j, jobErr, dur)
+		cur := edb.Reload(rc, j)
+		if cur == nil || !cur.Status.IsRunning() || cur.Attempt != j.Attempt {
+			flogger.Log(rc, "job %s %v attempt %d has been reaped while running", j.Kind, j.ID, j.Attempt)
+			return
+		}
+		if jobErr == nil && kind.Method.OutType != nil {
+			cur.RawResult = j.RawResult
+		}
+		if cur.ParamsVersion < j.ParamsVersion {
+			cur.RawParams, cur.ParamsVersion = j.RawParams, j.ParamsVersion // persist upgraded params
+		}
+		app.markJobCompleted(rc, kind, cur, jobErr, dur)
 	})
-	return true
 }
 
 func (app *App) markJobCompleted(rc *RC, kind *mvpjobs.Kind, j *mvpjobs.Job, jobErr error, dur time.Duration) {
@@ -212,6 +543,11 @@ func (app *App) markJobCompleted(rc *RC, kind *mvpjobs.Kind, j *mvpjobs.Job, job
 		j.LastDuration = dur
 		j.TotalDuration += dur
 	}
+	if j.CancelRequested {
+		app.markJobCancelled(rc, j)
+		recordJobCompletion(j, "cancelled", dur)
+		return
+	}
 	now := app.Now()
 
 	var jobImpl *JobImpl
@@ -232,13 +568,14 @@ func (app *App) markJobCompleted(rc *RC, kind *mvpjobs.Kind, j *mvpjobs.Job, job
 		j.TotalFailures++
 		delay := kind.Backoff.DelayAfter(j.ConsecFailures)
 		if delay >= backoff.InfiniteDelay {
-			if jobImpl.RepeatInterval > 0 {
-				// cron jobs never fail, they fall back to their repeat interval
+			if next := jobImpl.NextRepeatTime(now); !next.IsZero() {
+				// cron jobs never fail, they fall back to their next scheduled run
 				j.Status = mvpjobs.StatusQueued
-				j.NextRunTime = now.Add(jobImpl.RepeatInterval)
+				j.NextRunTime = next
 			} else {
 				j.Status = mvpjobs.StatusFailed
 				j.NextRunTime = time.Time{}
+				j.FinishTime = now
 			}
 		} else {
 			j.Status = mvpjobs.StatusRetrying
@@ -248,26 +585,44 @@ func (app *App) markJobCompleted(rc *RC, kind *mvpjobs.Kind, j *mvpjobs.Job, job
 		j.LastSuccessTime = now
 		j.LastErr = ""
 		j.ConsecFailures = 0
-		if jobImpl.RepeatInterval > 0 {
+		j.Step = ""
+		j.RawState = nil
+		if j.Status == mvpjobs.StatusRunningPending {
+			// re-enqueued while running, NextRunTime has been set by reenqueue
 			j.Status = mvpjobs.StatusQueued
-			j.NextRunTime = now.Add(jobImpl.RepeatInterval)
+			if len(j.Deps) > 0 {
+				app.updateBlockedJob(rc, j)
+			}
+		} else if next := jobImpl.NextRepeatTime(now); !next.IsZero() {
+			j.Status = mvpjobs.StatusQueued
+			j.NextRunTime = next
 		} else {
 			j.Status = mvpjobs.StatusDone
+			j.FinishTime = now
 		}
 	}
 	edb.Put(rc, j)
+	recordJobCompletion(j, jobOutcome(j, jobErr), dur)
+
+	if jobErr == nil {
+		runHooksFwd2(app.Hooks.jobSucceeded, rc, j)
+	} else if j.Status == mvpjobs.StatusFailed {
+		runHooksFwd3(app.Hooks.jobFailed, rc, j, jobErr)
+	} else {
+		runHooksFwd3(app.Hooks.jobRetrying, rc, j, jobErr)
+	}
+	app.unblockDependentJobs(rc, j)
 }
 
-func (app *App) executeJob(ctx context.Context, jid mvpjobs.JobID, kind *mvpjobs.Kind, name string, rawParams []byte, attempt int, workerIdx, workerCount int) error {
-	in := kind.Method.NewIn().(mvpjobs.Params)
-	err := json.Unmarshal(rawParams, in)
+func (app *App) executeJob(ctx context.Context, kind *mvpjobs.Kind, j *mvpjobs.Job, workerIdx, workerCount int) error {
+	in, err := decodeJobParams(kind, j)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal job params into %v: %w", kind.Method.InType, err)
+		return err
 	}
-	in.SetJobName(name)
 
-	rc := NewRC(ctx, app, fmt.Sprintf("jobs:w%d:%s:%v:%d", workerIdx, kind.Name, jid, attempt))
+	rc := NewRC(ctx, app, fmt.Sprintf("jobs:w%d:%s:%v:%d", workerIdx, kind.Name, j.ID, j.Attempt))
 	defer rc.Close()
+	rc.job = j
 	// rc.Auth = bm.Auth{
 	// 	Type: bm.ActorTypeAdmin,
 	// }
@@ -277,25 +632,148 @@ func (app *App) executeJob(ctx context.Context, jid mvpjobs.JobID, kind *mvpjobs
 		panic(fmt.Errorf("no impl registered for job %v", kind.Name))
 	}
 
-	_, err = app.doCall(rc, m, in)
-	return err
+	out, err := app.doCall(rc, m, in)
+	if err != nil {
+		return err
+	}
+	if kind.Method.OutType != nil {
+		raw, err := json.Marshal(out)
+		if err != nil {
+			return fmt.Errorf("failed to marshal job result %T: %w", out, err)
+		}
+		if string(raw) == "null" {
+			raw = nil
+		}
+		j.RawResult = raw
+	}
+	return nil
 }
 
-func (app *App) dequeuePendingJob(rc *RC) *mvpjobs.Job {
-	var j *mvpjobs.Job
+// decodeJobParams upgrades the params of j to the current version if needed,
+// and decodes them.
+func decodeJobParams(kind *mvpjobs.Kind, j *mvpjobs.Job) (mvpjobs.Params, error) {
+	raw, err := kind.UpgradeParams(j.RawParams, j.ParamsVersion)
+	if err != nil {
+		return nil, err
+	}
+	j.RawParams, j.ParamsVersion = raw, kind.ParamsVersion
+
+	in := kind.Method.NewIn().(mvpjobs.Params)
+	err = json.Unmarshal(j.RawParams, in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job params into %v: %w", kind.Method.InType, err)
+	}
+	in.SetJobName(j.Name)
+	return in, nil
+}
+
+// dequeuePendingJobs picks the highest-priority earliest due job of the given
+// set whose kind isn't at its MaxConcurrency or rate limit and which isn't
+// waiting for an earlier job of its partition, and marks it as started.
+// For kinds with a batch handler, it picks up to BatchSize such jobs of
+// the same kind. If due jobs have been skipped because of rate limits,
+// retryAt is the earliest time one of them can run.
+//
+// Only the due jobs of the set's kinds are visited. Jobs of kinds that
+// aren't registered in this process are left for the processes that know
+// about them.
+func (app *App) dequeuePendingJobs(rc *RC, set string) (jobs []*mvpjobs.Job, retryAt time.Time) {
 	rc.MustWrite(func() {
-		c := edb.IndexScan[mvpjobs.Job](rc, pendingJobsByRunTime, edb.FullScan())
-		if c.Next() {
-			j = c.Row()
-			if j.NextRunTime.After(rc.Now()) {
-				// flogger.Log(rc, "dequeued job %s %v, which is in the future", j.Kind, j.ID)
-				j = nil
-			} else {
-				app.markJobStarted(rc, j)
+		var running map[string]int
+		var cands []*mvpjobs.Job
+		for kind := range app.jobsByKind {
+			if set != allJobSets && kind.Set != set {
+				continue
+			}
+			if kind.MaxConcurrency > 0 {
+				if running == nil {
+					running = app.countRunningJobsByKind(rc)
+				}
+				if running[kind.Name] >= kind.MaxConcurrency {
+					continue
+				}
 			}
+			app.scanDueJobs(rc, kind, func(j *mvpjobs.Job) bool {
+				if j.Partition != "" && !app.isFirstInPartition(rc, j) {
+					return true
+				}
+				cands = append(cands, j)
+				return false
+			})
+		}
+		slices.SortFunc(cands, mvpjobs.CompareQueueOrder)
+
+		for _, first := range cands {
+			kind := app.JobSchema.KindByName(first.Kind)
+			if ok, t := app.allowJobByRateLimit(rc, kind); !ok {
+				if !t.IsZero() && (retryAt.IsZero() || t.Before(retryAt)) {
+					retryAt = t
+				}
+				continue
+			}
+			jobs = append(jobs, first)
+			if kind.BatchSize > 1 {
+				app.scanDueJobs(rc, kind, func(j *mvpjobs.Job) bool {
+					if j.ID == first.ID || (j.Partition != "" && !app.isFirstInPartition(rc, j)) {
+						return true
+					}
+					if kind.MaxConcurrency > 0 && running[kind.Name]+len(jobs) >= kind.MaxConcurrency {
+						return false
+					}
+					if ok, _ := app.allowJobByRateLimit(rc, kind); !ok {
+						return false
+					}
+					jobs = append(jobs, j)
+					return len(jobs) < kind.BatchSize
+				})
+			}
+			break
+		}
+		for _, j := range jobs {
+			app.markJobStarted(rc, j)
 		}
 	})
-	return j
+	return jobs, retryAt
+}
+
+// scanDueJobs calls f for the due jobs of the given kind in queue order until
+// f returns false. For each priority, it stops at the first job that isn't
+// due yet.
+func (app *App) scanDueJobs(rc *RC, kind *mvpjobs.Kind, f func(j *mvpjobs.Job) bool) {
+	now := rc.Now()
+	lower := mvpjobs.QueueKey{Kind: kind.Name, RunTime: time.Unix(0, 0)} // zero time encodes as a huge value
+	for {
+		next := edb.First(edb.IndexScan[mvpjobs.Job](rc, pendingJobsByQueueKey, edb.LowerBoundScan(lower, true)))
+		if next == nil || next.Kind != kind.Name {
+			return
+		}
+		lower.Rank = mvpjobs.QueueRank(next.Priority)
+		upper := mvpjobs.QueueKey{Kind: kind.Name, Rank: lower.Rank, RunTime: now}
+		for c := edb.RangeIndexScan[mvpjobs.Job](rc, pendingJobsByQueueKey, lower, upper, true, true); c.Next(); {
+			if !f(c.Row()) {
+				return
+			}
+		}
+		if lower.Rank == math.MaxUint64 {
+			return
+		}
+		lower.Rank++
+	}
+}
+
+// isFirstInPartition reports whether j is the earliest enqueued unfinished job
+// of its partition. Later jobs wait for it to finish, even while it's retrying.
+func (app *App) isFirstInPartition(rc *RC, j *mvpjobs.Job) bool {
+	first := edb.First(edb.PrefixIndexScan[mvpjobs.Job](rc, activeJobsByPartition, 1, mvpjobs.PartitionSlot{Partition: j.Partition}))
+	return first == nil || first.ID == j.ID
+}
+
+func (app *App) countRunningJobsByKind(rc *RC) map[string]int {
+	counts := make(map[string]int)
+	for c := edb.FullIndexScan[mvpjobs.Job](rc, runningJobsByStartTime); c.Next(); {
+		counts[c.Row().Kind]++
+	}
+	return counts
 }
 
 func (app *App) markJobStarted(rc *RC, j *mvpjobs.Job) {
@@ -304,7 +782,40 @@ func (app *App) markJobStarted(rc *RC, j *mvpjobs.Job) {
 	j.Status = mvpjobs.StatusRunning
 	j.StartTime = now
 	j.LastAttemptTime = now
+	j.HeartbeatTime = time.Time{}
 	edb.Put(rc, j)
+	runHooksFwd2(app.Hooks.jobStarted, rc, j)
+}
+
+// NextPendingJobTime returns the earliest NextRunTime later than the given
+// time of all queued and retrying jobs, or zero time if there are none.
+// Jobs due earlier are skipped, because they either run right away or wait
+// for something other than time (a partition, a concurrency or rate limit).
+func (app *App) NextPendingJobTime(txish edb.Txish, after time.Time) time.Time {
+	j := edb.First(edb.IndexScan[mvpjobs.Job](txish, pendingJobsByRunTime, edb.LowerBoundScan(after, false)))
+	if j == nil {
+		return time.Time{}
+	}
+	return j.NextRunTime
+}
+
+// JobResult decodes the result of the last successful run of the given job
+// into out, which must be a pointer to the kind's result type (or the result
+// type itself if it is a pointer). Returns false if there's no such job or it
+// hasn't succeeded yet.
+func (app *App) JobResult(txish edb.Txish, kind *mvpjobs.Kind, name string, out any) (bool, error) {
+	outType := kind.Method.OutType
+	if outType == nil {
+		panic(fmt.Errorf("%s: job handler does not return a result", kind.Name))
+	}
+	if t := reflect.TypeOf(out); t == nil || t.Kind() != reflect.Ptr || (t.Elem() != outType && t != outType) {
+		panic(fmt.Errorf("%s: JobResult requires *%v, got %T", kind.Name, kind.Method.OutType, out))
+	}
+	j := app.Job(txish, kind, name)
+	if j == nil {
+		return false, nil
+	}
+	return j.DecodeResult(out)
 }
 
 func (app *App) Job(txish edb.Txish, kind *mvpjobs.Kind, name string) *mvpjobs.Job {
@@ -0,0 +1,413 @@
+package mvp_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/edb"
+	"github.com/andreyvit/mvp"
+	"github.com/andreyvit/mvp/backoff"
+	"github.com/andreyvit/mvp/mvpjobs"
+	"github.com/andreyvit/mvp/mvpjobstest"
+	mvpm "github.com/andreyvit/mvp/mvpmodel"
+)
+
+// blockingJob defines a job that reports its RC via started, and then waits
+// for its context to be cancelled.
+func blockingJob(scm *mvpjobs.Schema, started chan<- *mvp.RC) *mvpjobs.Kind {
+	return scm.Define("Blocking", func(rc *mvp.RC, in *testJobParams) error {
+		started <- rc
+		<-rc.Done()
+		return context.Cause(rc)
+	}, mvpjobs.Idempotent, mvpm.Manual)
+}
+
+func TestCancelJob_running(t *testing.T) {
+	started := make(chan *mvp.RC, 1)
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = blockingJob(scm, started)
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "a"})
+	})
+
+	done := make(chan int)
+	go func() { done <- h.RunDue() }()
+	jrc := <-started
+
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.CancelJob(rc, kind, "a")
+		if jrc.Err() != nil {
+			t.Errorf("** handler cancelled before the transaction committed")
+		}
+	})
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("** handler not cancelled")
+	}
+	h.ExpectStatus(kind, "a", mvpjobs.StatusCancelled, 1)
+}
+
+func TestCancelJob_rolledBack(t *testing.T) {
+	started := make(chan *mvp.RC, 1)
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = blockingJob(scm, started)
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "a"})
+	})
+
+	done := make(chan int)
+	go func() { done <- h.RunDue() }()
+	jrc := <-started
+
+	rc := mvp.NewRC(h.Ctx, h.App, "test")
+	defer rc.Close()
+	err := rc.TryWrite(func() error {
+		h.App.CancelJob(rc, kind, "a")
+		return errors.New("rollback")
+	})
+	if err == nil {
+		t.Fatalf("** TryWrite succeeded")
+	}
+	if jrc.Err() != nil {
+		t.Errorf("** handler cancelled although the transaction was rolled back")
+	}
+	h.ExpectStatus(kind, "a", mvpjobs.StatusRunning, 1)
+
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.CancelJob(rc, kind, "a")
+	})
+	<-done
+}
+
+func TestCancelJob_fromAnotherProcess(t *testing.T) {
+	defer mvp.SetJobHeartbeatInterval(10 * time.Millisecond)()
+
+	started := make(chan *mvp.RC, 1)
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = blockingJob(scm, started)
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "a"})
+	})
+
+	done := make(chan int)
+	go func() { done <- h.RunDue() }()
+	<-started
+
+	// another process only sets the flag, as it has no access to the context
+	mustWrite(h, func(rc *mvp.RC) {
+		j := h.App.Job(rc, kind, "a")
+		j.CancelRequested = true
+		edb.Put(rc, j)
+	})
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("** handler not cancelled by heartbeat")
+	}
+	h.ExpectStatus(kind, "a", mvpjobs.StatusCancelled, 1)
+}
+
+func TestCancelJob_queued(t *testing.T) {
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = scm.Define("Noop", func(rc *mvp.RC, in *testJobParams) error {
+			t.Errorf("** cancelled job ran")
+			return nil
+		}, mvpjobs.Idempotent)
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "a"})
+		h.App.CancelJob(rc, kind, "a")
+	})
+	h.ExpectStatus(kind, "a", mvpjobs.StatusCancelled, 0)
+	if n := h.RunDue(); n != 0 {
+		t.Errorf("** RunDue = %d, wanted 0", n)
+	}
+}
+
+func TestJobs_priorities(t *testing.T) {
+	var order []string
+	record := func(rc *mvp.RC, in *testJobParams) error {
+		order = append(order, rc.CurrentJob().Kind+":"+in.Name)
+		return nil
+	}
+	var lo, hi *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		lo = scm.Define("Lo", record, mvpjobs.Repeatable)
+		hi = scm.Define("Hi", record, mvpjobs.Repeatable, mvpjobs.WithPriority(5))
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, lo, &testJobParams{Name: "a"})
+		h.App.Enqueue(rc, hi, &testJobParams{Name: "b"})
+		h.App.Enqueue(rc, lo, &testJobParams{Name: "c"}, mvpjobs.WithPriority(10))
+		h.App.Enqueue(rc, hi, &testJobParams{Name: "d"})
+	})
+	h.RunDue()
+	if a, e := strings.Join(order, " "), "Lo:c Hi:b Hi:d Lo:a"; a != e {
+		t.Errorf("** order = %s, wanted %s", a, e)
+	}
+
+	// a finished job gets the kind's priority back, unless overridden again
+	order = nil
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, lo, &testJobParams{Name: "c"})
+		h.App.Enqueue(rc, hi, &testJobParams{Name: "b"})
+		h.App.Enqueue(rc, lo, &testJobParams{Name: "a"}, mvpjobs.WithPriority(10))
+	})
+	if p := h.Job(lo, "c").Priority; p != 0 {
+		t.Errorf("** re-enqueued Lo:c priority = %d, wanted 0", p)
+	}
+	h.RunDue()
+	if a, e := strings.Join(order, " "), "Lo:a Hi:b Lo:c"; a != e {
+		t.Errorf("** order = %s, wanted %s", a, e)
+	}
+}
+
+func TestJobs_delayedEnqueue(t *testing.T) {
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = scm.Define("Later", func(rc *mvp.RC, in *testJobParams) error {
+			return nil
+		}, mvpjobs.Idempotent)
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.EnqueueAfter(rc, kind, &testJobParams{Name: "a"}, time.Hour)
+		h.App.EnqueueAfter(rc, kind, &testJobParams{Name: "a"}, 2*time.Hour) // keeps the earlier time
+		h.App.EnqueueAfter(rc, kind, &testJobParams{Name: "b"}, time.Hour)
+		h.App.EnqueueAfter(rc, kind, &testJobParams{Name: "b"}, 2*time.Hour, mvpjobs.KeepLaterRunTime)
+	})
+	h.ExpectNextRunTime(kind, "a", testStartTime.Add(time.Hour))
+	h.ExpectNextRunTime(kind, "b", testStartTime.Add(2*time.Hour))
+
+	if n := h.RunDue(); n != 0 {
+		t.Errorf("** RunDue = %d, wanted 0", n)
+	}
+	h.Advance(time.Hour)
+	h.ExpectStatus(kind, "a", mvpjobs.StatusDone, 1)
+	h.ExpectStatus(kind, "b", mvpjobs.StatusQueued, 0)
+	h.Advance(time.Hour)
+	h.ExpectStatus(kind, "b", mvpjobs.StatusDone, 1)
+}
+
+func TestJobs_retries(t *testing.T) {
+	var failures int
+	var failed []string
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = scm.Define("Flaky", func(rc *mvp.RC, in *testJobParams) error {
+			if failures < in.Value {
+				failures++
+				return errors.New("boom")
+			}
+			return nil
+		}, mvpjobs.Idempotent, backoff.Backoff{FixedDelayRetries: 2, FixedDelay: time.Minute})
+	})
+	h.App.Hooks.JobFailed(func(rc *mvp.RC, j *mvpjobs.Job, err error) {
+		failed = append(failed, j.Name+": "+err.Error())
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "a", Value: 2})
+	})
+	h.Advance(time.Hour)
+	h.ExpectStatus(kind, "a", mvpjobs.StatusDone, 3)
+	h.ExpectTransitions(kind, "a",
+		mvpjobstest.Transition{Status: mvpjobs.StatusRunning, Attempt: 1, NextRunTime: testStartTime},
+		mvpjobstest.Transition{Status: mvpjobs.StatusRetrying, Attempt: 1, NextRunTime: testStartTime.Add(time.Minute)},
+		mvpjobstest.Transition{Status: mvpjobs.StatusRunning, Attempt: 2, NextRunTime: testStartTime.Add(time.Minute)},
+		mvpjobstest.Transition{Status: mvpjobs.StatusRetrying, Attempt: 2, NextRunTime: testStartTime.Add(2 * time.Minute)},
+		mvpjobstest.Transition{Status: mvpjobs.StatusRunning, Attempt: 3, NextRunTime: testStartTime.Add(2 * time.Minute)},
+		mvpjobstest.Transition{Status: mvpjobs.StatusDone, Attempt: 3, NextRunTime: testStartTime.Add(2 * time.Minute)},
+	)
+
+	failures = 0
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "b", Value: 10})
+	})
+	h.Advance(time.Hour)
+	h.ExpectStatus(kind, "b", mvpjobs.StatusFailed, 3)
+	if a, e := strings.Join(failed, "; "), "b: boom"; a != e {
+		t.Errorf("** JobFailed calls = %q, wanted %q", a, e)
+	}
+}
+
+type testJobReport struct {
+	URL string `json:"url"`
+}
+
+func TestJobResult(t *testing.T) {
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = scm.Define("Report", func(rc *mvp.RC, in *testJobParams) (*testJobReport, error) {
+			return &testJobReport{URL: "/reports/" + in.Name}, nil
+		}, mvpjobs.Idempotent)
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "a"})
+	})
+
+	rc := mvp.NewRC(h.Ctx, h.App, "test")
+	defer rc.Close()
+	var report *testJobReport
+	rc.MustRead(func() {
+		if ok, err := h.App.JobResult(rc, kind, "a", &report); ok || err != nil {
+			t.Errorf("** JobResult before run = %v, %v, wanted false, nil", ok, err)
+		}
+	})
+
+	h.RunDue()
+	rc.MustRead(func() {
+		if ok, err := h.App.JobResult(rc, kind, "a", &report); !ok || err != nil {
+			t.Fatalf("** JobResult = %v, %v, wanted true, nil", ok, err)
+		}
+	})
+	if e := "/reports/a"; report.URL != e {
+		t.Errorf("** URL = %q, wanted %q", report.URL, e)
+	}
+}
+
+type testAccountJobParams struct {
+	Name    string `json:"-"`
+	Account string `json:"account"`
+}
+
+func (p *testAccountJobParams) JobName() string             { return p.Name }
+func (p *testAccountJobParams) SetJobName(name string)      { p.Name = name }
+func (p *testAccountJobParams) JobAccountID() mvpjobs.JobID { return 0 }
+
+func TestJobs_partitions(t *testing.T) {
+	var order []string
+	fail := true
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = scm.Define("Sync", func(rc *mvp.RC, in *testAccountJobParams) error {
+			order = append(order, in.Name)
+			if in.Name == "a1" && fail {
+				fail = false
+				return errors.New("boom")
+			}
+			return nil
+		}, mvpjobs.Idempotent, backoff.Backoff{FixedDelayRetries: 1, FixedDelay: time.Minute},
+			mvpjobs.WithPartitionKey(func(in mvpjobs.Params) string {
+				return in.(*testAccountJobParams).Account
+			}))
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, kind, &testAccountJobParams{Name: "a1", Account: "A"})
+		h.App.Enqueue(rc, kind, &testAccountJobParams{Name: "a2", Account: "A"})
+		h.App.Enqueue(rc, kind, &testAccountJobParams{Name: "b1", Account: "B"})
+	})
+	h.RunDue()
+	h.ExpectStatus(kind, "a1", mvpjobs.StatusRetrying, 1)
+	h.ExpectStatus(kind, "a2", mvpjobs.StatusQueued, 0) // waits for a1 to finish
+	h.Advance(time.Minute)
+	if a, e := strings.Join(order, " "), "a1 b1 a1 a2"; a != e {
+		t.Errorf("** order = %s, wanted %s", a, e)
+	}
+}
+
+type testJobParamsV1 struct {
+	Name   string   `json:"-"`
+	Emails []string `json:"emails"`
+}
+
+func (p *testJobParamsV1) JobName() string             { return p.Name }
+func (p *testJobParamsV1) SetJobName(name string)      { p.Name = name }
+func (p *testJobParamsV1) JobAccountID() mvpjobs.JobID { return 0 }
+
+func TestJobs_paramsUpgrade(t *testing.T) {
+	var got []string
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = scm.Define("Notify", func(rc *mvp.RC, in *testJobParamsV1) error {
+			got = in.Emails
+			return nil
+		}, mvpjobs.Idempotent, mvpjobs.WithParamsVersion(1),
+			mvpjobs.WithParamsUpgrade{From: 0, Upgrade: func(p map[string]any) error {
+				p["emails"] = []any{p["email"]}
+				delete(p, "email")
+				return nil
+			}})
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		j := h.App.Enqueue(rc, kind, &testJobParamsV1{Name: "a"})
+		j.RawParams, j.ParamsVersion = []byte(`{"email":"a@example.com"}`), 0 // stored by an old version
+		edb.Put(rc, j)
+	})
+	h.RunDue()
+	h.ExpectStatus(kind, "a", mvpjobs.StatusDone, 1)
+	if a, e := strings.Join(got, ","), "a@example.com"; a != e {
+		t.Errorf("** emails = %q, wanted %q", a, e)
+	}
+	if j := h.Job(kind, "a"); j.ParamsVersion != 1 || string(j.RawParams) != `{"emails":["a@example.com"]}` {
+		t.Errorf("** stored params = v%d %s, wanted upgraded", j.ParamsVersion, j.RawParams)
+	}
+}
+
+func TestJobs_maxConcurrency(t *testing.T) {
+	var batches []string
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = scm.Define("Sync", func(rc *mvp.RC, ins []*testAccountJobParams) ([]error, error) {
+			var names []string
+			for _, in := range ins {
+				names = append(names, in.Name)
+			}
+			batches = append(batches, strings.Join(names, "+"))
+			return nil, nil
+		}, mvpjobs.Idempotent, mvpjobs.WithBatchSize(2), mvpjobs.WithMaxConcurrency(2),
+			mvpjobs.WithPartitionKey(func(in mvpjobs.Params) string {
+				return in.(*testAccountJobParams).Account
+			}))
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, kind, &testAccountJobParams{Name: "a1", Account: "A"})
+		h.App.Enqueue(rc, kind, &testAccountJobParams{Name: "a2", Account: "A"})
+		h.App.Enqueue(rc, kind, &testAccountJobParams{Name: "b1", Account: "B"})
+	})
+	h.RunDue()
+	// a2 waits for a1, and must not take up a slot that b1 can use
+	if a, e := strings.Join(batches, " "), "a1+b1 a2"; a != e {
+		t.Errorf("** batches = %s, wanted %s", a, e)
+	}
+}
+
+func TestSeedCronJobs(t *testing.T) {
+	var interval, scheduled *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		noop := func(rc *mvp.RC, in *mvpjobs.NoParams) error {
+			return nil
+		}
+		interval = scm.Define("Interval", noop, mvpjobs.Cron, mvpjobs.WithRepeatInterval(time.Hour))
+		scheduled = scm.Define("Scheduled", noop, mvpjobs.Cron, mvpjobs.WithSchedule("0 3 * * *"))
+	})
+
+	h.App.SeedCronJobs(h.Ctx)
+	h.ExpectNextRunTime(interval, "", testStartTime)
+	h.ExpectNextRunTime(scheduled, "", testStartTime.Add(3*time.Hour))
+
+	h.Advance(30 * time.Minute)
+	h.ExpectStatus(interval, "", mvpjobs.StatusQueued, 1)
+	h.ExpectNextRunTime(interval, "", testStartTime.Add(time.Hour))
+
+	// a restart keeps the planned runs
+	h.App.SeedCronJobs(h.Ctx)
+	h.ExpectNextRunTime(interval, "", testStartTime.Add(time.Hour))
+	h.ExpectNextRunTime(scheduled, "", testStartTime.Add(3*time.Hour))
+
+	// a redeploy with earlier runs moves them
+	h.App.JobImplOf(interval).RepeatInterval = 10 * time.Minute
+	h.App.JobImplOf(scheduled).Schedule = mvpjobs.MustParseSchedule("0 1 * * *", nil)
+	h.App.SeedCronJobs(h.Ctx)
+	h.ExpectNextRunTime(interval, "", testStartTime.Add(40*time.Minute))
+	h.ExpectNextRunTime(scheduled, "", testStartTime.Add(time.Hour))
+}
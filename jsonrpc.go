@@ -0,0 +1,165 @@
+package mvp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/andreyvit/mvp/flogger"
+	mvpm "github.com/andreyvit/mvp/mvpmodel"
+	"github.com/andreyvit/mvp/mvprpc"
+)
+
+// JSON-RPC 2.0 error codes.
+const (
+	jsonRPCParseError     = -32700
+	jsonRPCInvalidRequest = -32600
+	jsonRPCMethodNotFound = -32601
+	jsonRPCInvalidParams  = -32602
+	jsonRPCServerError    = -32000 // application errors
+)
+
+// maxJSONRPCBatchSize is the maximum number of calls in a JSON-RPC batch.
+const maxJSONRPCBatchSize = 100
+
+type jsonRPCInput struct {
+	Body []byte `json:"-" form:",rawbody"`
+}
+
+type jsonRPCRequest struct {
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type jsonRPCResponse struct {
+	Version string          `json:"jsonrpc"`
+	Result  any             `json:"result,omitempty"`
+	Error   *jsonRPCError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// JSONRPCRoute adds a JSON-RPC 2.0 endpoint at the given path that calls
+// the methods of the given APIs implemented via MethodImpl by name,
+// including in batches. Params must be passed by name.
+//
+// Each call runs in its own transaction according to the method's
+// StoreAffinity. Errors are reported with code -32000 and a data member
+// in the shape produced by BuildAPIErrorResponse, except for unknown methods
+// and invalid params, which use the standard codes.
+func JSONRPCRoute(b *RouteBuilder, path string, apis ...*mvprpc.API) *Route {
+	if len(apis) == 0 {
+		panic(fmt.Errorf("JSONRPCRoute %s: no APIs to expose", path))
+	}
+	exposed := make(map[*mvprpc.Method]bool)
+	for _, api := range apis {
+		for _, method := range api.Methods() {
+			exposed[method] = true
+		}
+	}
+	return b.Route("mvp.jsonrpc", "POST "+path, func(rc *RC, in *jsonRPCInput) (*RawOutput, error) {
+		return rc.app.serveJSONRPC(rc, exposed, in.Body), nil
+	}, mvpm.Manual)
+}
+
+func (app *App) serveJSONRPC(rc *RC, exposed map[*mvprpc.Method]bool, body []byte) *RawOutput {
+	body = bytes.TrimSpace(body)
+	var output any
+	if len(body) > 0 && body[0] == '[' {
+		var reqs []json.RawMessage
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			output = newJSONRPCErrorResponse(nil, jsonRPCParseError, err.Error())
+		} else if len(reqs) == 0 || len(reqs) > maxJSONRPCBatchSize {
+			output = newJSONRPCErrorResponse(nil, jsonRPCInvalidRequest, fmt.Sprintf("batch must contain between 1 and %d calls", maxJSONRPCBatchSize))
+		} else {
+			var resps []*jsonRPCResponse
+			for _, raw := range reqs {
+				if resp := app.callJSONRPC(rc, exposed, raw); resp != nil {
+					resps = append(resps, resp)
+				}
+			}
+			if resps != nil {
+				output = resps
+			}
+		}
+	} else if resp := app.callJSONRPC(rc, exposed, body); resp != nil {
+		output = resp
+	}
+
+	if output == nil {
+		return &RawOutput{} // notifications only
+	}
+	return &RawOutput{
+		Data:        must(json.Marshal(output)),
+		ContentType: "application/json",
+	}
+}
+
+// callJSONRPC performs a single call, returning nil for notifications.
+func (app *App) callJSONRPC(rc *RC, exposed map[*mvprpc.Method]bool, raw json.RawMessage) *jsonRPCResponse {
+	var req jsonRPCRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		if _, ok := err.(*json.SyntaxError); ok {
+			return newJSONRPCErrorResponse(nil, jsonRPCParseError, err.Error())
+		}
+		return newJSONRPCErrorResponse(nil, jsonRPCInvalidRequest, err.Error())
+	}
+	if req.Version != "2.0" || req.Method == "" {
+		return newJSONRPCErrorResponse(req.ID, jsonRPCInvalidRequest, `jsonrpc must be "2.0" and method must be set`)
+	}
+
+	resp := app.doJSONRPC(rc, exposed, &req)
+	if req.ID == nil {
+		return nil // notification
+	}
+	return resp
+}
+
+func (app *App) doJSONRPC(rc *RC, exposed map[*mvprpc.Method]bool, req *jsonRPCRequest) *jsonRPCResponse {
+	m := app.methodsByName[req.Method]
+	if m == nil || !exposed[m.Method] {
+		return newJSONRPCErrorResponse(req.ID, jsonRPCMethodNotFound, "unknown method "+req.Method)
+	}
+
+	in := m.NewIn()
+	if len(req.Params) > 0 && !bytes.Equal(req.Params, []byte("null")) {
+		decoder := json.NewDecoder(bytes.NewReader(req.Params))
+		if rc.Request.Header.Get("X-Ignore-Unknown-Fields") != "yes" {
+			decoder.DisallowUnknownFields()
+		}
+		if err := decoder.Decode(in); err != nil {
+			return newJSONRPCErrorResponse(req.ID, jsonRPCInvalidParams, err.Error())
+		}
+	}
+
+	out, err := app.doCall(rc, m, in)
+	if err != nil {
+		flogger.Log(rc, "NOTICE: JSON-RPC %s failed: %v", req.Method, err)
+		apiErr := BuildAPIErrorResponse(err)
+		resp := newJSONRPCErrorResponse(req.ID, jsonRPCServerError, apiErr.PublicError())
+		resp.Error.Data = apiErr
+		return resp
+	}
+	if out == nil {
+		out = struct{}{} // result is required on success
+	}
+	return &jsonRPCResponse{Version: "2.0", Result: out, ID: req.ID}
+}
+
+func newJSONRPCErrorResponse(id json.RawMessage, code int, msg string) *jsonRPCResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &jsonRPCResponse{
+		Version: "2.0",
+		Error:   &jsonRPCError{Code: code, Message: msg},
+		ID:      id,
+	}
+}
@@ -0,0 +1,115 @@
+package mvp_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andreyvit/mvp"
+	"github.com/andreyvit/mvp/mvpjobstest"
+	"github.com/andreyvit/mvp/mvprpc"
+)
+
+type testRPCResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code int             `json:"code"`
+		Data json.RawMessage `json:"data"`
+	} `json:"error"`
+}
+
+func (r *testRPCResponse) String() string {
+	if r.Error != nil {
+		if r.Error.Data == nil {
+			return fmt.Sprintf("%s error %d", r.ID, r.Error.Code)
+		}
+		return fmt.Sprintf("%s error %d %s", r.ID, r.Error.Code, r.Error.Data)
+	}
+	return string(r.ID) + " " + string(r.Result)
+}
+
+func newJSONRPCTestApp(t *testing.T) *mvpjobstest.Harness {
+	api := &mvprpc.API{Name: "test"}
+	getUser := api.Method("users.get", &testGetUserIn{}, &testUser{})
+	other := &mvprpc.API{Name: "other"}
+	ping := other.Method("other.ping", nil, nil)
+
+	h := newTestApp(t, nil, withRoutes(func(b *mvp.RouteBuilder) {
+		mvp.JSONRPCRoute(b, "/rpc", api)
+	}))
+	h.App.MethodImpl(getUser, func(rc *mvp.RC, in *testGetUserIn) (*testUser, error) {
+		if in.ID == "" {
+			return nil, mvp.ErrForbidden.Msg("no such user")
+		}
+		return &testUser{ID: in.ID}, nil
+	})
+	h.App.MethodImpl(ping, func(rc *mvp.RC) error {
+		return nil
+	})
+	return h
+}
+
+// callJSONRPC posts body and returns the responses, one per line.
+func callJSONRPC(t *testing.T, h *mvpjobstest.Harness, body string) string {
+	w := serve(h, httptest.NewRequest("POST", testBaseURL+"/rpc", strings.NewReader(body)))
+	if w.Code != 200 {
+		t.Fatalf("** %s: HTTP %d %s", body, w.Code, w.Body.String())
+	}
+	raw := strings.TrimSpace(w.Body.String())
+	var resps []*testRPCResponse
+	var err error
+	if strings.HasPrefix(raw, "[") {
+		err = json.Unmarshal([]byte(raw), &resps)
+	} else if raw != "" {
+		resps = append(resps, new(testRPCResponse))
+		err = json.Unmarshal([]byte(raw), resps[0])
+	}
+	if err != nil {
+		t.Fatalf("** %s: invalid response %s: %v", body, raw, err)
+	}
+	var lines []string
+	for _, resp := range resps {
+		lines = append(lines, resp.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestJSONRPC(t *testing.T) {
+	h := newJSONRPCTestApp(t)
+	tests := []struct {
+		name, body, expected string
+	}{
+		{"call", `{"jsonrpc":"2.0","method":"users.get","params":{"id":"u1"},"id":1}`,
+			`1 {"id":"u1","manager":null}`},
+		{"app error", `{"jsonrpc":"2.0","method":"users.get","params":{"id":""},"id":"x"}`,
+			`"x" error -32000 {"error":"forbidden","message":"no such user"}`},
+		{"notification", `{"jsonrpc":"2.0","method":"users.get","params":{"id":"u1"}}`,
+			``},
+		{"batch", `[{"jsonrpc":"2.0","method":"users.get","params":{"id":"u1"},"id":1},` +
+			`{"jsonrpc":"2.0","method":"users.get","params":{"id":"u2"}},` +
+			`{"jsonrpc":"2.0","method":"users.get","params":{"id":"u3"},"id":3}]`,
+			"1 {\"id\":\"u1\",\"manager\":null}\n3 {\"id\":\"u3\",\"manager\":null}"},
+		{"parse error", `{"jsonrpc":`,
+			`null error -32700`},
+		{"invalid request", `{"jsonrpc":"1.0","method":"users.get","id":1}`,
+			`1 error -32600`},
+		{"empty batch", `[]`,
+			`null error -32600`},
+		{"unknown method", `{"jsonrpc":"2.0","method":"users.delete","id":1}`,
+			`1 error -32601`},
+		{"method of another API", `{"jsonrpc":"2.0","method":"other.ping","id":1}`,
+			`1 error -32601`},
+		{"invalid params", `{"jsonrpc":"2.0","method":"users.get","params":{"user_id":"u1"},"id":1}`,
+			`1 error -32602`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if a := callJSONRPC(t, h, tt.body); a != tt.expected {
+				t.Errorf("** got:\n%s\nwanted:\n%s", a, tt.expected)
+			}
+		})
+	}
+}
@@ -1,10 +1,14 @@
 package mvpjobs
 
 import (
+	"cmp"
 	"encoding/json"
+	"fmt"
+	"math"
 	"time"
 
 	"github.com/andreyvit/mvp/flake"
+	"github.com/vmihailenco/msgpack/v5"
 )
 
 type JobID = flake.ID
@@ -14,16 +18,28 @@ type Job struct {
 	Kind      string          `msgpack:"k"`
 	Name      string          `msgpack:"n"`
 	RawParams json.RawMessage `msgpack:"p"`
+	// ParamsVersion is the Kind.ParamsVersion that RawParams were encoded with.
+	ParamsVersion int `msgpack:"pv,omitempty"`
 	// Streams   []string        `msgpack:"str,omitempty"`
 
 	Status      Status    `msgpack:"s2,omitempty"`
 	NextRunTime time.Time `msgpack:"rt,omitempty"`
+	Priority    int       `msgpack:"pr,omitempty"`
 	Step        string    `msgpack:"sp,omitempty"`
 	RawState    []byte    `msgpack:"st,omitempty"`
 
+	// RawResult is the JSON-encoded result of the last successful run.
+	RawResult json.RawMessage `msgpack:"res,omitempty"`
+
+	CancelRequested bool       `msgpack:"cxl,omitempty"`
+	Deps            []KindName `msgpack:"deps,omitempty"`
+	Partition       string     `msgpack:"pk,omitempty"`
+	PartitionSeq    uint64     `msgpack:"pseq,omitempty"`
+
 	EnqueueTime     time.Time `msgpack:"tq,omitempty"`
 	StartTime       time.Time `msgpack:"ts,omitempty"`
 	LastAttemptTime time.Time `msgpack:"ta,omitempty"`
+	HeartbeatTime   time.Time `msgpack:"thb,omitempty"`
 	FinishTime      time.Time `msgpack:"tf,omitempty"`
 	LastSuccessTime time.Time `msgpack:"tls,omitempty"`
 	LastFailureTime time.Time `msgpack:"tlf,omitempty"`
@@ -44,6 +60,118 @@ func (j *Job) IsAnonymous() bool {
 	return j.Name == ""
 }
 
+// DecodeResult decodes the result of the last successful run into ptr,
+// returning false if there's no result yet.
+func (j *Job) DecodeResult(ptr any) (bool, error) {
+	if len(j.RawResult) == 0 {
+		return false, nil
+	}
+	err := json.Unmarshal(j.RawResult, ptr)
+	if err != nil {
+		return false, fmt.Errorf("job %s %v: failed to decode result into %T: %w", j.Kind, j.ID, ptr, err)
+	}
+	return true, nil
+}
+
+// LastAliveTime returns the last time a running job is known to have been
+// making progress.
+func (j *Job) LastAliveTime() time.Time {
+	if j.HeartbeatTime.After(j.StartTime) {
+		return j.HeartbeatTime
+	}
+	return j.StartTime
+}
+
+// SetState records a checkpoint: a step label and a msgpack-encoded state value.
+func (j *Job) SetState(step string, state any) {
+	j.Step = step
+	if state == nil {
+		j.RawState = nil
+	} else {
+		j.RawState = must(msgpack.Marshal(state))
+	}
+}
+
+// DecodeState decodes the checkpointed state into ptr, returning false if
+// no state has been recorded.
+func (j *Job) DecodeState(ptr any) (bool, error) {
+	if len(j.RawState) == 0 {
+		return false, nil
+	}
+	err := msgpack.Unmarshal(j.RawState, ptr)
+	if err != nil {
+		return false, fmt.Errorf("job %s %v: failed to decode state into %T: %w", j.Kind, j.ID, ptr, err)
+	}
+	return true, nil
+}
+
+// QueueKey orders pending jobs of each kind by descending priority, then by
+// run time.
+type QueueKey struct {
+	Kind    string
+	Rank    uint64
+	RunTime time.Time
+}
+
+// QueueRank maps priorities onto ascending unsigned ranks, highest priority
+// first; uint64 wraparound keeps negative priorities in order.
+func QueueRank(priority int) uint64 {
+	return uint64(math.MaxInt64) - uint64(int64(priority))
+}
+
+func (j *Job) QueueKey() QueueKey {
+	return QueueKey{j.Kind, QueueRank(j.Priority), j.NextRunTime}
+}
+
+// CompareQueueOrder orders pending jobs of different kinds the way QueueKey
+// orders those of the same kind, breaking ties by enqueue order.
+func CompareQueueOrder(a, b *Job) int {
+	if c := cmp.Compare(QueueRank(a.Priority), QueueRank(b.Priority)); c != 0 {
+		return c
+	}
+	if c := a.NextRunTime.Compare(b.NextRunTime); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// FinishKey orders finished jobs by kind, status and finish time.
+type FinishKey struct {
+	Kind       string
+	Status     Status
+	FinishTime time.Time
+}
+
+func (j *Job) FinishKey() FinishKey {
+	t := j.FinishTime
+	if t.IsZero() {
+		t = j.LastAttemptTime // finished before FinishTime has been recorded
+	}
+	if t.IsZero() {
+		t = j.EnqueueTime
+	}
+	return FinishKey{j.Kind, j.Status, t}
+}
+
+// PartitionSlot orders jobs within a partition by enqueue order.
+type PartitionSlot struct {
+	Partition string
+	Seq       uint64
+}
+
+func (j *Job) PartitionSlot() PartitionSlot {
+	return PartitionSlot{j.Partition, j.PartitionSeq}
+}
+
+type KindStatus struct {
+	Kind   string
+	Status Status
+}
+
+func (j *Job) KindStatus() KindStatus {
+	return KindStatus{j.Kind, j.Status}
+}
+
 type KindName struct {
 	Kind string
 	Name string
@@ -53,6 +181,13 @@ func (kn KindName) IsAnonymous() bool {
 	return kn.Name == ""
 }
 
+func (kn KindName) String() string {
+	if kn.Name == "" {
+		return kn.Kind
+	}
+	return kn.Kind + ":" + kn.Name
+}
+
 type Set struct {
 	schema *Schema
 	name   string
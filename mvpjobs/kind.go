@@ -1,6 +1,8 @@
 package mvpjobs
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"time"
@@ -11,22 +13,99 @@ import (
 )
 
 type Kind struct {
-	schema         *Schema
-	Name           string
-	Behavior       Behavior
-	Persistence    Persistence
-	Method         *mvprpc.Method
-	Set            string
-	RepeatInterval time.Duration
-	Backoff        backoff.Backoff
-	Enabled        bool
-	Handler        any
+	schema          *Schema
+	Name            string
+	Behavior        Behavior
+	Persistence     Persistence
+	Method          *mvprpc.Method
+	Set             string
+	MaxConcurrency  int
+	Priority        int
+	RepeatInterval  time.Duration
+	Schedule        *Schedule
+	Backoff         backoff.Backoff
+	Timeout         time.Duration
+	PartitionKey    func(in Params) string
+	ParamsVersion   int
+	RateLimit       *WithRateLimit
+	Overflow        Overflow
+	OverflowTimeout time.Duration
+	SpillTo         string
+	Enabled         bool
+	Handler         any
+	BatchHandler    any // func(rc, []*Params) ([]error, error), see Define
+	BatchSize       int
+
+	DoneRetention   time.Duration
+	FailedRetention time.Duration
+
+	paramsUpgrades map[int]func(params map[string]any) error
 }
 
 func (k *Kind) IsCron() bool {
 	return k.Behavior == Cron
 }
 
+// KindName returns a reference to a job of this kind with the given name.
+func (k *Kind) KindName(name string) KindName {
+	return KindName{k.Name, name}
+}
+
+// Retention returns how long to keep anonymous jobs of this kind that have
+// finished with the given status, or zero to keep them forever.
+func (k *Kind) Retention(status Status) time.Duration {
+	switch status {
+	case StatusDone:
+		return k.DoneRetention
+	case StatusFailed, StatusFailedSkipped, StatusCancelled:
+		return k.FailedRetention
+	default:
+		return 0
+	}
+}
+
+// Partition returns the partition key of a job with the given params, or
+// an empty string if jobs of this kind aren't partitioned.
+func (k *Kind) Partition(in Params) string {
+	if k.PartitionKey == nil || in == nil {
+		return ""
+	}
+	return k.PartitionKey(in)
+}
+
+// UpgradeParams brings params stored with the given version up to
+// ParamsVersion by applying the registered upgrades in order.
+func (k *Kind) UpgradeParams(raw json.RawMessage, version int) (json.RawMessage, error) {
+	if version == k.ParamsVersion {
+		return raw, nil
+	}
+	if version > k.ParamsVersion {
+		return nil, fmt.Errorf("%s: params version %d is newer than the current version %d", k.Name, version, k.ParamsVersion)
+	}
+
+	var params map[string]any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	err := dec.Decode(&params)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to decode params version %d: %w", k.Name, version, err)
+	}
+	if params == nil {
+		params = make(map[string]any)
+	}
+	for v := version; v < k.ParamsVersion; v++ {
+		upgrade := k.paramsUpgrades[v]
+		if upgrade == nil {
+			return nil, fmt.Errorf("%s: no params upgrade from version %d", k.Name, v)
+		}
+		err := upgrade(params)
+		if err != nil {
+			return nil, fmt.Errorf("%s: params upgrade from version %d: %w", k.Name, v, err)
+		}
+	}
+	return json.Marshal(params)
+}
+
 func (k *Kind) IsPersistent() bool {
 	return k.Persistence == Persistent
 }
@@ -62,35 +141,54 @@ func (scm *Schema) EphemeralKindNames() []string {
 	return names
 }
 
+var (
+	errorType      = reflect.TypeOf((*error)(nil)).Elem()
+	errorSliceType = reflect.SliceOf(errorType)
+)
+
 func isStructPtr(typ reflect.Type) bool {
 	return typ.Kind() == reflect.Ptr && typ.Elem().Kind() == reflect.Struct
 }
 
 func (scm *Schema) Define(kindName string, inOrFunc any, behavior Behavior, opts ...any) *Kind {
 	var handler any
-	var in any
+	var in, out any
 	if inOrFunc == nil {
 		inOrFunc = &NoParams{}
 	}
 	inTyp := reflect.TypeOf(inOrFunc)
+	var batchHandler any
 	if inTyp.Kind() == reflect.Func && inTyp.NumIn() == 2 && isStructPtr(inTyp.In(1)) {
 		handler = inOrFunc
 		in = reflect.New(inTyp.In(1).Elem()).Interface()
+		if inTyp.NumOut() == 2 {
+			out = reflect.Zero(inTyp.Out(0)).Interface()
+		}
+	} else if inTyp.Kind() == reflect.Func && inTyp.NumIn() == 2 && inTyp.In(1).Kind() == reflect.Slice && isStructPtr(inTyp.In(1).Elem()) {
+		if inTyp.NumOut() != 2 || inTyp.Out(0) != errorSliceType || inTyp.Out(1) != errorType {
+			panic(fmt.Errorf("%s: batch handler must return ([]error, error)", kindName))
+		}
+		batchHandler = inOrFunc
+		in = reflect.New(inTyp.In(1).Elem().Elem()).Interface()
 	} else if isStructPtr(inTyp) {
 		in = inOrFunc
 	} else {
 		panic(fmt.Errorf("invalid value for Define inOrFunc: %T %v", inOrFunc, inOrFunc))
 	}
 
+	var scheduleExpr string
+	var loc *time.Location
+
 	scm.init()
 	kind := &Kind{
-		schema:      scm,
-		Behavior:    behavior,
-		Name:        kindName,
-		Method:      scm.api.Method("Job"+kindName, in, nil),
-		Enabled:     true,
-		Persistence: Persistent,
-		Handler:     handler,
+		schema:       scm,
+		Behavior:     behavior,
+		Name:         kindName,
+		Method:       scm.api.Method("Job"+kindName, in, out),
+		Enabled:      true,
+		Persistence:  Persistent,
+		Handler:      handler,
+		BatchHandler: batchHandler,
 	}
 	for _, opt := range opts {
 		switch opt := opt.(type) {
@@ -98,18 +196,103 @@ func (scm *Schema) Define(kindName string, inOrFunc any, behavior Behavior, opts
 			kind.Persistence = opt
 		case Behavior:
 			kind.Behavior = opt
+		case Overflow:
+			kind.Overflow = opt
+		case WithOverflowTimeout:
+			kind.OverflowTimeout = time.Duration(opt)
+		case WithSpillTo:
+			kind.SpillTo = string(opt)
 		case backoff.Backoff:
 			kind.Backoff = opt
 		case mvpm.StoreAffinity:
 			kind.Method.StoreAffinity = opt
 		case WithRepeatInterval:
 			kind.RepeatInterval = time.Duration(opt)
+		case WithSchedule:
+			scheduleExpr = string(opt)
+		case WithTimeZone:
+			var err error
+			loc, err = time.LoadLocation(string(opt))
+			if err != nil {
+				panic(fmt.Errorf("%s: %w", kindName, err))
+			}
 		case WithEnabled:
 			kind.Enabled = bool(opt)
+		case WithSet:
+			kind.Set = string(opt)
+		case WithMaxConcurrency:
+			kind.MaxConcurrency = int(opt)
+		case WithPriority:
+			kind.Priority = int(opt)
+		case WithTimeout:
+			kind.Timeout = time.Duration(opt)
+		case WithBatchSize:
+			if batchHandler == nil {
+				panic(fmt.Errorf("%s: WithBatchSize requires a batch handler", kindName))
+			}
+			if opt <= 0 {
+				panic(fmt.Errorf("%s: WithBatchSize must be positive", kindName))
+			}
+			kind.BatchSize = int(opt)
+		case WithParamsVersion:
+			kind.ParamsVersion = int(opt)
+		case WithParamsUpgrade:
+			if opt.Upgrade == nil {
+				panic(fmt.Errorf("%s: WithParamsUpgrade from version %d has no Upgrade func", kindName, opt.From))
+			}
+			if kind.paramsUpgrades == nil {
+				kind.paramsUpgrades = make(map[int]func(params map[string]any) error)
+			}
+			if kind.paramsUpgrades[opt.From] != nil {
+				panic(fmt.Errorf("%s: duplicate WithParamsUpgrade from version %d", kindName, opt.From))
+			}
+			kind.paramsUpgrades[opt.From] = opt.Upgrade
+		case WithPartitionKey:
+			kind.PartitionKey = opt
+		case WithRateLimit:
+			if opt.PerSec <= 0 || opt.Burst <= 0 {
+				panic(fmt.Errorf("%s: WithRateLimit requires positive PerSec and Burst", kindName))
+			}
+			kind.RateLimit = &opt
+		case WithDoneRetention:
+			kind.DoneRetention = time.Duration(opt)
+		case WithFailedRetention:
+			kind.FailedRetention = time.Duration(opt)
 		default:
 			panic(fmt.Errorf("%s: unknown options %T %v", kindName, opt, opt))
 		}
 	}
+	if scheduleExpr != "" {
+		if kind.Behavior != Cron {
+			panic(fmt.Errorf("%s: WithSchedule requires Cron behavior", kindName))
+		}
+		var err error
+		kind.Schedule, err = ParseSchedule(scheduleExpr, loc)
+		if err != nil {
+			panic(fmt.Errorf("%s: %w", kindName, err))
+		}
+	} else if loc != nil {
+		panic(fmt.Errorf("%s: WithTimeZone requires WithSchedule", kindName))
+	}
+	if batchHandler != nil {
+		if kind.Persistence != Persistent {
+			panic(fmt.Errorf("%s: batch handlers require Persistent persistence", kindName))
+		}
+		if kind.BatchSize == 0 {
+			kind.BatchSize = DefaultBatchSize
+		}
+	}
+	if kind.Persistence != Ephemeral && (kind.Overflow != OverflowBlock || kind.OverflowTimeout != 0 || kind.SpillTo != "") {
+		panic(fmt.Errorf("%s: overflow options require Ephemeral persistence", kindName))
+	}
+	if (kind.Overflow == OverflowSpill) != (kind.SpillTo != "") {
+		panic(fmt.Errorf("%s: OverflowSpill and WithSpillTo must be used together", kindName))
+	}
+	for from := range kind.paramsUpgrades {
+		if from < 0 || from >= kind.ParamsVersion {
+			panic(fmt.Errorf("%s: WithParamsUpgrade from version %d, but WithParamsVersion is %d", kindName, from, kind.ParamsVersion))
+		}
+	}
 	// for _, tag := range strings.Fields(tags) {
 	// 	scm.byTag[tag] = append(scm.byTag[tag], kind)
 	// }
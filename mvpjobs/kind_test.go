@@ -0,0 +1,93 @@
+package mvpjobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/andreyvit/mvp/flake"
+)
+
+type upgradeTestParams struct {
+	AccountID flake.ID `json:"account_id"`
+	Emails    []string `json:"emails"`
+}
+
+func (upgradeTestParams) JobName() string          { return "" }
+func (upgradeTestParams) SetJobName(name string)   {}
+func (p upgradeTestParams) JobAccountID() flake.ID { return p.AccountID }
+
+func TestUpgradeParams(t *testing.T) {
+	var scm Schema
+	kind := scm.Define("Notify", &upgradeTestParams{}, Repeatable,
+		WithParamsVersion(2),
+		WithParamsUpgrade{From: 0, Upgrade: func(p map[string]any) error {
+			p["account_id"] = p["acc"]
+			delete(p, "acc")
+			return nil
+		}},
+		WithParamsUpgrade{From: 1, Upgrade: func(p map[string]any) error {
+			email, ok := p["email"].(string)
+			if !ok {
+				return fmt.Errorf("missing email")
+			}
+			p["emails"] = []string{email}
+			delete(p, "email")
+			return nil
+		}})
+
+	tests := []struct {
+		raw     string
+		version int
+		e       string
+	}{
+		{`{"acc":9007199254740993,"email":"a@example.com"}`, 0, `{"account_id":9007199254740993,"emails":["a@example.com"]}`},
+		{`{"account_id":1,"email":"a@example.com"}`, 1, `{"account_id":1,"emails":["a@example.com"]}`},
+		{`{"account_id":1,"emails":["a@example.com"]}`, 2, `{"account_id":1,"emails":["a@example.com"]}`},
+		{`{"account_id":1}`, 1, `error: Notify: params upgrade from version 1: missing email`},
+		{`{}`, 3, `error: Notify: params version 3 is newer than the current version 2`},
+	}
+	for _, tt := range tests {
+		raw, err := kind.UpgradeParams(json.RawMessage(tt.raw), tt.version)
+		var a string
+		if err != nil {
+			a = "error: " + err.Error()
+		} else {
+			a = string(raw)
+		}
+		if a != tt.e {
+			t.Errorf("UpgradeParams(%s, %d) = %s, wanted %s", tt.raw, tt.version, a, tt.e)
+		}
+	}
+}
+
+func TestUpgradeParamsMissing(t *testing.T) {
+	var scm Schema
+	kind := scm.Define("Notify", &upgradeTestParams{}, Repeatable,
+		WithParamsVersion(2),
+		WithParamsUpgrade{From: 1, Upgrade: func(p map[string]any) error { return nil }})
+	_, err := kind.UpgradeParams(json.RawMessage(`{}`), 0)
+	if a, e := fmt.Sprint(err), "Notify: no params upgrade from version 0"; a != e {
+		t.Errorf("got %s, wanted %s", a, e)
+	}
+}
+
+func TestDefineBatchHandler(t *testing.T) {
+	var scm Schema
+	kind := scm.Define("Digest", func(rc any, ins []*upgradeTestParams) ([]error, error) { return nil, nil }, Repeatable)
+	if kind.BatchHandler == nil || kind.Handler != nil {
+		t.Errorf("batch handler not recognized")
+	}
+	if a, e := kind.BatchSize, DefaultBatchSize; a != e {
+		t.Errorf("BatchSize = %d, wanted %d", a, e)
+	}
+	if a, e := kind.Method.InPtrType, reflect.TypeOf(&upgradeTestParams{}); a != e {
+		t.Errorf("InPtrType = %v, wanted %v", a, e)
+	}
+
+	kind = scm.Define("Digest2", func(rc any, ins []*upgradeTestParams) ([]error, error) { return nil, nil }, Repeatable, WithBatchSize(10))
+	if a, e := kind.BatchSize, 10; a != e {
+		t.Errorf("BatchSize = %d, wanted %d", a, e)
+	}
+}
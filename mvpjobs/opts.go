@@ -1,8 +1,68 @@
 package mvpjobs
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+)
 
 type (
 	WithRepeatInterval time.Duration
+	WithSchedule       string // cron expression, see Schedule
+	WithTimeZone       string // IANA time zone name for WithSchedule, defaults to UTC
 	WithEnabled        bool
+	WithSet            string // name of the worker pool to run on, see Settings.WorkerSets
+	WithMaxConcurrency int    // max number of jobs of the kind running at once
+	WithPriority       int    // higher priority jobs run first; also accepted by Enqueue
+	WithTimeout        time.Duration
+	WithParamsVersion  int // current version of the params struct, see WithParamsUpgrade
+	WithBatchSize      int // max number of jobs passed to a batch handler at once, DefaultBatchSize by default
+
+	// WithOverflowTimeout limits how long OverflowBlock waits for room in
+	// the ephemeral queue; zero waits forever.
+	WithOverflowTimeout time.Duration
+	// WithSpillTo names the persistent kind that OverflowSpill enqueues
+	// instead, with the params passed to EnqueueEphemeral.
+	WithSpillTo string
+
+	// WithPartitionKey derives a partition key from job params. Jobs sharing
+	// a non-empty key run one at a time in enqueue order, even across kinds.
+	WithPartitionKey func(in Params) string
+
+	// How long to keep finished anonymous jobs; zero means forever.
+	WithDoneRetention   time.Duration
+	WithFailedRetention time.Duration // also applies to skipped and cancelled jobs
 )
+
+// WithRateLimit holds back jobs of the kind that would exceed the given
+// token-bucket rate, without counting it as an attempt. If PerSet is true,
+// the bucket is shared by all kinds of the set, which must use identical
+// limits. Limits are enforced per process.
+type WithRateLimit struct {
+	PerSec rate.Limit
+	Burst  int
+	PerSet bool
+}
+
+// DefaultBatchSize is the number of jobs passed to a batch handler at once
+// unless WithBatchSize says otherwise.
+const DefaultBatchSize = 100
+
+// WithParamsUpgrade rewrites params stored by version From into the shape
+// expected by version From+1. Params are passed as decoded JSON objects
+// (numbers as json.Number) and modified in place. Upgrades run in order
+// before a job executes, so jobs enqueued by older deploys keep working.
+type WithParamsUpgrade struct {
+	From    int
+	Upgrade func(params map[string]any) error
+}
+
+// PartitionByAccount runs jobs of the same account one at a time, see
+// Params.JobAccountID.
+var PartitionByAccount = WithPartitionKey(func(in Params) string {
+	if id := in.JobAccountID(); id != 0 {
+		return fmt.Sprintf("account:%v", id)
+	}
+	return ""
+})
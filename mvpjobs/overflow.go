@@ -0,0 +1,46 @@
+package mvpjobs
+
+import (
+	"fmt"
+
+	"golang.org/x/exp/slices"
+)
+
+// Overflow decides what EnqueueEphemeral does when the ephemeral job queue
+// is full. Pass it to Define along with Ephemeral.
+type Overflow int
+
+const (
+	OverflowBlock      Overflow = 0 // wait for room, up to WithOverflowTimeout
+	OverflowDropOldest Overflow = 1 // drop the longest-waiting job of the same kind
+	OverflowReject     Overflow = 2 // fail with ErrEphemeralQueueFull
+	OverflowSpill      Overflow = 3 // enqueue a WithSpillTo persistent job instead
+)
+
+var _overflowStrings = []string{
+	"block",
+	"drop-oldest",
+	"reject",
+	"spill",
+}
+
+func (v Overflow) String() string {
+	return _overflowStrings[v]
+}
+
+func ParseOverflow(s string) (Overflow, error) {
+	if i := slices.Index(_overflowStrings, s); i >= 0 {
+		return Overflow(i), nil
+	} else {
+		return 0, fmt.Errorf("invalid Overflow %q", s)
+	}
+}
+
+func (v Overflow) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+func (v *Overflow) UnmarshalText(b []byte) error {
+	var err error
+	*v, err = ParseOverflow(string(b))
+	return err
+}
@@ -24,3 +24,4 @@ type NoParams struct{}
 
 func (_ NoParams) JobName() string        { return "" }
 func (_ NoParams) SetJobName(name string) {}
+func (_ NoParams) JobAccountID() flake.ID { return 0 }
@@ -2,6 +2,7 @@ package mvpjobs
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -13,5 +14,75 @@ type Request struct {
 }
 
 type EnqueueOptions struct {
-	RunTime time.Time `msgpack:"rt,omitempty"`
+	RunTime         time.Time       `msgpack:"rt,omitempty"`
+	RunTimeConflict RunTimeConflict `msgpack:"rtc,omitempty"`
+	WaitFor         []KindName      `msgpack:"deps,omitempty"`
+	Priority        *int            `msgpack:"pr,omitempty"`
+}
+
+func (eo *EnqueueOptions) RunTimeOr(now time.Time) time.Time {
+	if eo.RunTime.IsZero() {
+		return now
+	}
+	return eo.RunTime
+}
+
+func (eo *EnqueueOptions) PriorityOr(def int) int {
+	if eo.Priority == nil {
+		return def
+	}
+	return *eo.Priority
+}
+
+func (eo *EnqueueOptions) Apply(opts ...any) {
+	for _, opt := range opts {
+		switch opt := opt.(type) {
+		case WithRunTime:
+			eo.RunTime = time.Time(opt)
+		case RunTimeConflict:
+			eo.RunTimeConflict = opt
+		case WaitFor:
+			eo.WaitFor = append(eo.WaitFor, opt...)
+		case WithPriority:
+			p := int(opt)
+			eo.Priority = &p
+		default:
+			panic(fmt.Errorf("unknown enqueue option %T %v", opt, opt))
+		}
+	}
+}
+
+// WaitFor is an enqueue option that keeps a job blocked until all of the given
+// jobs are done. If any of them fails, is cancelled or doesn't exist, the job
+// is skipped, so enqueue the dependencies first. Only named jobs of kinds that
+// don't repeat can be waited for. When an existing pending job is enqueued
+// again, it waits for the given jobs in addition to its earlier dependencies;
+// enqueueing a finished or running job with WaitFor panics unless that
+// schedules another run.
+type WaitFor []KindName
+
+// WithRunTime is an enqueue option that delays the job until the given time.
+type WithRunTime time.Time
+
+// RunTimeConflict is an enqueue option that decides what happens when
+// a pending job is enqueued again with a different run time.
+type RunTimeConflict int
+
+const (
+	KeepEarlierRunTime RunTimeConflict = iota
+	KeepLaterRunTime
+	ReplaceRunTime
+)
+
+func (v RunTimeConflict) ShouldReplace(existing, requested time.Time) bool {
+	switch v {
+	case KeepEarlierRunTime:
+		return existing.After(requested)
+	case KeepLaterRunTime:
+		return requested.After(existing)
+	case ReplaceRunTime:
+		return !requested.Equal(existing)
+	default:
+		panic(fmt.Errorf("invalid RunTimeConflict %d", v))
+	}
 }
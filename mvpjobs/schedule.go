@@ -0,0 +1,193 @@
+package mvpjobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed 5-field cron expression (minute, hour, day of month,
+// month, day of week) evaluated in a particular time zone.
+//
+// Supports *, lists, ranges, steps, JAN-DEC and SUN-SAT names, 7 as an alias
+// for Sunday, and @yearly, @monthly, @weekly, @daily and @hourly macros.
+// Like Vixie cron, when both day of month and day of week are restricted
+// (don't start with *, so */2 counts as unrestricted), a day matching either
+// one matches.
+type Schedule struct {
+	Expr string
+	Loc  *time.Location
+
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+var scheduleMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = []string{"", "JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+	dowNames   = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+)
+
+func ParseSchedule(expr string, loc *time.Location) (*Schedule, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	s := &Schedule{Expr: expr, Loc: loc}
+
+	spec := strings.TrimSpace(expr)
+	if m, ok := scheduleMacros[strings.ToLower(spec)]; ok {
+		spec = m
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	var err error
+	if s.minute, _, err = parseScheduleField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: minute: %w", expr, err)
+	}
+	if s.hour, _, err = parseScheduleField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: hour: %w", expr, err)
+	}
+	if s.dom, s.domStar, err = parseScheduleField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day of month: %w", expr, err)
+	}
+	if s.month, _, err = parseScheduleField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: month: %w", expr, err)
+	}
+	if s.dow, s.dowStar, err = parseScheduleField(fields[4], 0, 7, dowNames); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day of week: %w", expr, err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow = (s.dow | 1) &^ (1 << 7)
+	}
+	return s, nil
+}
+
+func MustParseSchedule(expr string, loc *time.Location) *Schedule {
+	return must(ParseSchedule(expr, loc))
+}
+
+func (s *Schedule) String() string {
+	if s.Loc == nil || s.Loc == time.UTC {
+		return s.Expr
+	}
+	return fmt.Sprintf("%s (%s)", s.Expr, s.Loc)
+}
+
+// Next returns the earliest matching time strictly after the given one,
+// or zero time if there's none within the next 5 years.
+func (s *Schedule) Next(after time.Time) time.Time {
+	loc := s.Loc
+	if loc == nil {
+		loc = time.UTC
+	}
+	t := after.In(loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + 5
+	for t.Year() <= limit {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = laterOf(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+		} else if !s.matchesDay(t) {
+			t = laterOf(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+		} else if s.hour&(1<<uint(t.Hour())) == 0 {
+			// not using time.Date to step over DST gaps
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		} else if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+		} else {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// laterOf guards against time.Date normalizing a non-existent local midnight
+// to a time that isn't after t.
+func laterOf(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(time.Minute)
+}
+
+func (s *Schedule) matchesDay(t time.Time) bool {
+	domOK := s.dom&(1<<uint(t.Day())) != 0
+	dowOK := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
+
+// parseScheduleField returns the bits of the matching values, and whether
+// the field is unrestricted for the purposes of the day matching rule, which,
+// like in Vixie cron, holds for any field starting with *, including */n.
+func parseScheduleField(field string, min, max int, names []string) (bits uint64, star bool, err error) {
+	star = strings.HasPrefix(field, "*")
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			step, err = strconv.Atoi(stepStr)
+			if err != nil || step <= 0 {
+				return 0, false, fmt.Errorf("invalid step %q", stepStr)
+			}
+		}
+
+		var lo, hi int
+		if rng == "*" {
+			lo, hi = min, max
+		} else {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			lo, err = parseScheduleValue(loStr, min, max, names)
+			if err != nil {
+				return 0, false, err
+			}
+			if isRange {
+				hi, err = parseScheduleValue(hiStr, min, max, names)
+				if err != nil {
+					return 0, false, err
+				}
+				if hi < lo {
+					return 0, false, fmt.Errorf("invalid range %q", rng)
+				}
+			} else if hasStep {
+				hi = max
+			} else {
+				hi = lo
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, star, nil
+}
+
+func parseScheduleValue(s string, min, max int, names []string) (int, error) {
+	for i, name := range names {
+		if name != "" && strings.EqualFold(s, name) {
+			return i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", v, min, max)
+	}
+	return v, nil
+}
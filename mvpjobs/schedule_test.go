@@ -0,0 +1,78 @@
+package mvpjobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	tests := []struct {
+		expr  string
+		loc   *time.Location
+		after string
+		e     string
+	}{
+		{"* * * * *", nil, "2023-05-10T10:20:30Z", "2023-05-10T10:21:00Z"},
+		{"0 3 * * *", nil, "2023-05-10T10:20:00Z", "2023-05-11T03:00:00Z"},
+		{"0 3 * * *", nil, "2023-05-10T03:00:00Z", "2023-05-11T03:00:00Z"},
+		{"0 3 * * *", nil, "2023-05-10T02:59:59Z", "2023-05-10T03:00:00Z"},
+		{"0 3 * * MON", nil, "2023-05-10T10:20:00Z", "2023-05-15T03:00:00Z"},
+		{"0 3 * * 7", nil, "2023-05-10T10:20:00Z", "2023-05-14T03:00:00Z"},
+		{"*/15 9-17 * * mon-fri", nil, "2023-05-12T17:50:00Z", "2023-05-15T09:00:00Z"},
+		{"30 */6 * * *", nil, "2023-05-10T07:00:00Z", "2023-05-10T12:30:00Z"},
+		{"0 0 1,15 * *", nil, "2023-05-02T00:00:00Z", "2023-05-15T00:00:00Z"},
+		{"0 0 13 * FRI", nil, "2023-05-02T00:00:00Z", "2023-05-05T00:00:00Z"},
+		{"0 0 */2 * FRI", nil, "2023-05-02T00:00:00Z", "2023-05-05T00:00:00Z"},
+		{"0 0 */2 * FRI", nil, "2023-05-05T00:00:00Z", "2023-05-19T00:00:00Z"},
+		{"0 0 13 * */2", nil, "2023-05-02T00:00:00Z", "2023-05-13T00:00:00Z"},
+		{"0 0 */10 * */3", nil, "2023-05-02T00:00:00Z", "2023-05-21T00:00:00Z"},
+		{"0 0 29 FEB *", nil, "2023-01-01T00:00:00Z", "2024-02-29T00:00:00Z"},
+		{"@monthly", nil, "2023-12-10T00:00:00Z", "2024-01-01T00:00:00Z"},
+		{"0 3 * * *", ny, "2023-05-10T10:20:00Z", "2023-05-11T07:00:00Z"},
+		{"0 3 * * *", ny, "2023-12-10T10:20:00Z", "2023-12-11T08:00:00Z"},
+		{"30 2 * * *", ny, "2023-03-11T12:00:00Z", "2023-03-13T06:30:00Z"}, // skipped by DST
+		{"30 1 * * *", ny, "2023-11-05T05:00:00Z", "2023-11-05T05:30:00Z"},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.expr, tt.loc)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		a := s.Next(must(time.Parse(time.RFC3339, tt.after))).UTC().Format(time.RFC3339)
+		if a != tt.e {
+			t.Errorf("%v: Next(%s) = %s, wanted %s", s, tt.after, a, tt.e)
+		}
+	}
+}
+
+func TestScheduleNextNever(t *testing.T) {
+	s := MustParseSchedule("0 0 31 2 *", nil)
+	if a := s.Next(time.Now()); !a.IsZero() {
+		t.Errorf("Next = %v, wanted zero", a)
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"x * * * *",
+	} {
+		if _, err := ParseSchedule(expr, nil); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded, wanted error", expr)
+		}
+	}
+}
@@ -19,6 +19,7 @@ const (
 	StatusDone
 	StatusFailed
 	StatusFailedSkipped
+	StatusCancelled
 )
 
 var _statusStrings = []string{
@@ -32,6 +33,7 @@ var _statusStrings = []string{
 	"done",
 	"failed",
 	"skipped",
+	"cancelled",
 }
 
 func (s Status) IsPending() bool {
@@ -43,7 +45,7 @@ func (s Status) IsRunning() bool {
 }
 
 func (s Status) IsTerminal() bool {
-	return s == StatusDone || s == StatusFailed || s == StatusFailedSkipped
+	return s == StatusDone || s == StatusFailed || s == StatusFailedSkipped || s == StatusCancelled
 }
 
 func (v Status) String() string {
@@ -0,0 +1,35 @@
+package mvpjobstest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a manually advanced clock to be passed as mvp.AppOptions.Now.
+type Clock struct {
+	mut sync.Mutex
+	now time.Time
+}
+
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.now
+}
+
+func (c *Clock) Set(t time.Time) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.now = t
+}
+
+func (c *Clock) Advance(d time.Duration) time.Time {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.now = c.now.Add(d)
+	return c.now
+}
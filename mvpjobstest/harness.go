@@ -0,0 +1,191 @@
+// Package mvpjobstest drives the persistent job queue of an mvp.App
+// deterministically, using a fake clock instead of sleeping.
+//
+//	clock := mvpjobstest.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+//	app.Initialize(settings, mvp.AppOptions{Now: clock.Now})
+//	h := mvpjobstest.New(t, app, clock)
+//	... enqueue jobs ...
+//	h.RunDue()
+//	h.ExpectStatus(kind, "foo", mvpjobs.StatusRetrying, 1)
+//	h.Advance(time.Hour)
+//	h.ExpectTransitions(kind, "foo", ...)
+package mvpjobstest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/mvp"
+	"github.com/andreyvit/mvp/mvpjobs"
+)
+
+// Transition is a status change of a job observed via job lifecycle hooks.
+type Transition struct {
+	Status      mvpjobs.Status
+	Attempt     int
+	NextRunTime time.Time
+}
+
+func (tr Transition) String() string {
+	if tr.NextRunTime.IsZero() {
+		return fmt.Sprintf("%v#%d", tr.Status, tr.Attempt)
+	}
+	return fmt.Sprintf("%v#%d@%s", tr.Status, tr.Attempt, tr.NextRunTime.Format(time.RFC3339))
+}
+
+type Harness struct {
+	T     testing.TB
+	App   *mvp.App
+	Clock *Clock
+	Ctx   context.Context
+
+	mut         sync.Mutex
+	transitions map[mvpjobs.KindName][]Transition
+}
+
+// New returns a harness for the given app, which must have been initialized
+// with clock.Now as mvp.AppOptions.Now. Adds job lifecycle hooks to the app
+// to record transitions.
+func New(t testing.TB, app *mvp.App, clock *Clock) *Harness {
+	h := &Harness{
+		T:           t,
+		App:         app,
+		Clock:       clock,
+		Ctx:         context.Background(),
+		transitions: make(map[mvpjobs.KindName][]Transition),
+	}
+	app.Hooks.JobStarted(h.record)
+	app.Hooks.JobSucceeded(h.record)
+	app.Hooks.JobRetrying(h.recordFailure)
+	app.Hooks.JobFailed(h.recordFailure)
+	return h
+}
+
+func (h *Harness) recordFailure(rc *mvp.RC, j *mvpjobs.Job, err error) {
+	h.record(rc, j)
+}
+
+func (h *Harness) record(rc *mvp.RC, j *mvpjobs.Job) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	kn := j.KindName()
+	h.transitions[kn] = append(h.transitions[kn], Transition{
+		Status:      j.Status,
+		Attempt:     j.Attempt,
+		NextRunTime: j.NextRunTime,
+	})
+}
+
+// RunDue runs all jobs due at the current clock time, returning the number
+// of jobs executed.
+func (h *Harness) RunDue() int {
+	return h.App.RunPendingJobs(h.Ctx)
+}
+
+// Advance moves the clock forward by d, running due jobs along the way.
+func (h *Harness) Advance(d time.Duration) int {
+	return h.AdvanceTo(h.Clock.Now().Add(d))
+}
+
+// AdvanceTo moves the clock forward to the given time. Rather than jumping
+// straight there, it stops at the NextRunTime of every pending job that
+// becomes due in between, so retries and repeats run exactly when they would
+// in production, and then runs the jobs due at the target time.
+func (h *Harness) AdvanceTo(target time.Time) int {
+	count := h.RunDue()
+	for {
+		next := h.nextPendingJobTime()
+		if next.IsZero() || next.After(target) {
+			break
+		}
+		h.Clock.Set(next)
+		count += h.RunDue()
+	}
+	if target.After(h.Clock.Now()) {
+		h.Clock.Set(target)
+		count += h.RunDue()
+	}
+	return count
+}
+
+func (h *Harness) nextPendingJobTime() time.Time {
+	rc := mvp.NewRC(h.Ctx, h.App, "jobstest")
+	defer rc.Close()
+	var next time.Time
+	rc.MustRead(func() {
+		next = h.App.NextPendingJobTime(rc, h.Clock.Now())
+	})
+	return next
+}
+
+// Job returns the current state of the given job, or nil if it does not exist.
+func (h *Harness) Job(kind *mvpjobs.Kind, name string) *mvpjobs.Job {
+	rc := mvp.NewRC(h.Ctx, h.App, "jobstest")
+	defer rc.Close()
+	var j *mvpjobs.Job
+	rc.MustRead(func() {
+		j = h.App.Job(rc, kind, name)
+	})
+	return j
+}
+
+func (h *Harness) mustJob(kind *mvpjobs.Kind, name string) *mvpjobs.Job {
+	h.T.Helper()
+	j := h.Job(kind, name)
+	if j == nil {
+		h.T.Fatalf("** job %s not found", mvpjobs.KindName{Kind: kind.Name, Name: name})
+	}
+	return j
+}
+
+func (h *Harness) ExpectStatus(kind *mvpjobs.Kind, name string, status mvpjobs.Status, attempt int) {
+	h.T.Helper()
+	j := h.mustJob(kind, name)
+	if j.Status != status || j.Attempt != attempt {
+		h.T.Errorf("** job %s is %v at attempt %d, wanted %v at attempt %d (last error: %s)", j.KindName(), j.Status, j.Attempt, status, attempt, j.LastErr)
+	}
+}
+
+// ExpectNextRunTime checks the job's NextRunTime; pass zero time to check
+// that the job isn't scheduled to run.
+func (h *Harness) ExpectNextRunTime(kind *mvpjobs.Kind, name string, t time.Time) {
+	h.T.Helper()
+	j := h.mustJob(kind, name)
+	if !j.NextRunTime.Equal(t) {
+		h.T.Errorf("** job %s NextRunTime = %v, wanted %v", j.KindName(), j.NextRunTime, t)
+	}
+}
+
+// Transitions returns the transitions recorded for the given job so far.
+func (h *Harness) Transitions(kind *mvpjobs.Kind, name string) []Transition {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return append([]Transition(nil), h.transitions[mvpjobs.KindName{Kind: kind.Name, Name: name}]...)
+}
+
+// ExpectTransitions checks all transitions recorded for the given job so far,
+// and then forgets them, so that subsequent calls only check new ones.
+func (h *Harness) ExpectTransitions(kind *mvpjobs.Kind, name string, expected ...Transition) {
+	h.T.Helper()
+	kn := mvpjobs.KindName{Kind: kind.Name, Name: name}
+	h.mut.Lock()
+	actual := h.transitions[kn]
+	delete(h.transitions, kn)
+	h.mut.Unlock()
+
+	if a, e := formatTransitions(actual), formatTransitions(expected); a != e {
+		h.T.Errorf("** job %s transitions:\n\t%s\nwanted:\n\t%s", kn, a, e)
+	}
+}
+
+func formatTransitions(trs []Transition) string {
+	strs := make([]string, len(trs))
+	for i, tr := range trs {
+		strs[i] = tr.String()
+	}
+	return strings.Join(strs, "\n\t")
+}
@@ -0,0 +1,240 @@
+package mvp
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/andreyvit/edb"
+	"github.com/andreyvit/mvp/flake"
+	"github.com/andreyvit/mvp/mvpjobs"
+)
+
+const jobsCommandUsage = `Job commands:
+  jobs list [-kind K] [-status S] [-limit N]   list jobs
+  jobs show <kind> [<name>] | <id>              print a job's full record
+  jobs reenqueue <kind> [<name>] | <id>         re-enqueue a job like Enqueue would (per its kind's behavior)
+  jobs retry <kind> [<name>] | <id>             force a finished or retrying job to run again with fresh retries
+  jobs cancel <kind> [<name>] | <id>            cancel a queued or running job
+  jobs run <kind> [<json params>]               run a job synchronously in this process
+`
+
+const otherCommandsUsage = `Other commands:
+  openapi                                      print the OpenAPI document of the app
+  tsclient                                     print a TypeScript client for the RPC methods
+`
+
+func runCommand(ctx context.Context, app *App, args []string) error {
+	switch args[0] {
+	case "jobs":
+		return runJobsCommand(ctx, app, args[1:])
+	case "openapi":
+		return printJSON(app.OpenAPI())
+	case "tsclient":
+		_, err := os.Stdout.Write(app.TypeScriptClient())
+		return err
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func runJobsCommand(ctx context.Context, app *App, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing jobs subcommand\n\n%s", jobsCommandUsage)
+	}
+	rc := NewRC(ctx, app, "cmd")
+	defer rc.Close()
+
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "list":
+		return listJobsCommand(rc, args)
+	case "show":
+		var j *mvpjobs.Job
+		var err error
+		rc.MustRead(func() {
+			j, err = findJobForCommand(rc, args)
+		})
+		if err != nil {
+			return err
+		}
+		return printJSON(j)
+	case "reenqueue", "retry":
+		var j *mvpjobs.Job
+		var err error
+		rc.MustWrite(func() {
+			j, err = findJobForCommand(rc, args)
+			if err == nil {
+				kind := app.JobSchema.KindByName(j.Kind)
+				if kind == nil {
+					err = fmt.Errorf("unknown job kind %q", j.Kind)
+					return
+				}
+				if cmd == "retry" {
+					app.retryJob(rc, kind, j)
+				} else {
+					app.Reenqueue(rc, kind, j, nil, false)
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if j.NextRunTime.IsZero() {
+			fmt.Printf("%s %v: %v\n", j.KindName(), j.ID, j.Status)
+		} else {
+			fmt.Printf("%s %v: %v, next run at %v\n", j.KindName(), j.ID, j.Status, j.NextRunTime.Format(time.RFC3339))
+		}
+		return nil
+	case "cancel":
+		var j *mvpjobs.Job
+		var err error
+		rc.MustWrite(func() {
+			j, err = findJobForCommand(rc, args)
+			if err == nil {
+				app.cancelJob(rc, j)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if j.CancelRequested {
+			fmt.Printf("%s %v: cancellation requested, the job is running\n", j.KindName(), j.ID)
+		} else {
+			fmt.Printf("%s %v: %v\n", j.KindName(), j.ID, j.Status)
+		}
+		return nil
+	case "run":
+		if len(args) < 1 || len(args) > 2 {
+			return fmt.Errorf("usage: jobs run <kind> [<json params>]")
+		}
+		kind := app.JobSchema.KindByName(args[0])
+		if kind == nil {
+			return fmt.Errorf("unknown job kind %q", args[0])
+		}
+		if !kind.IsPersistent() {
+			return fmt.Errorf("%s is an ephemeral job kind, only persistent ones can be run", kind.Name)
+		}
+		in := kind.Method.NewIn().(mvpjobs.Params)
+		if len(args) > 1 {
+			dec := json.NewDecoder(strings.NewReader(args[1]))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(in); err != nil {
+				return fmt.Errorf("invalid params for %s: %w", kind.Name, err)
+			}
+		}
+		start := time.Now()
+		err := app.RunJob(ctx, kind, in)
+		if err != nil {
+			return fmt.Errorf("%s failed after %v: %w", kind.Name, time.Since(start), err)
+		}
+		fmt.Printf("%s done in %v\n", kind.Name, time.Since(start))
+		return nil
+	default:
+		return fmt.Errorf("unknown jobs subcommand %q\n\n%s", cmd, jobsCommandUsage)
+	}
+}
+
+func listJobsCommand(rc *RC, args []string) error {
+	app := rc.app
+	fs := flag.NewFlagSet("jobs list", flag.ContinueOnError)
+	kindName := fs.String("kind", "", "only list jobs of this kind")
+	statusStr := fs.String("status", "", "only list jobs with this status")
+	limit := fs.Int("limit", 100, "max number of jobs to list")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var status mvpjobs.Status
+	if *statusStr != "" {
+		var err error
+		status, err = mvpjobs.ParseStatus(*statusStr)
+		if err != nil {
+			return err
+		}
+	}
+	if *kindName != "" && app.JobSchema.KindByName(*kindName) == nil {
+		return fmt.Errorf("unknown job kind %q", *kindName)
+	}
+
+	var jobs []*mvpjobs.Job
+	rc.MustRead(func() {
+		jobs = app.FilterJobs(rc, *kindName, status, *limit)
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tKIND\tNAME\tSTATUS\tATTEMPT\tNEXT RUN\tLAST ERROR")
+	for _, j := range jobs {
+		var next string
+		if !j.NextRunTime.IsZero() {
+			next = j.NextRunTime.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%v\t%s\t%s\t%v\t%d\t%s\t%s\n", j.ID, j.Kind, j.Name, j.Status, j.Attempt, next, j.LastErr)
+	}
+	return w.Flush()
+}
+
+// FilterJobs returns up to limit jobs of the given kind and status, newest
+// first; an empty kind or StatusNone matches any.
+func (app *App) FilterJobs(txish edb.Txish, kindName string, status mvpjobs.Status, limit int) []*mvpjobs.Job {
+	var c edb.Cursor[mvpjobs.Job]
+	switch {
+	case kindName != "" && status != mvpjobs.StatusNone && !status.IsTerminal():
+		c = edb.ReverseExactIndexScan[mvpjobs.Job](txish, activeJobsByKindStatus, mvpjobs.KindStatus{Kind: kindName, Status: status})
+	case kindName != "":
+		c = edb.ReverseExactIndexScan[mvpjobs.Job](txish, jobsByKind, kindName)
+	default:
+		c = edb.FullReverseTableScan[mvpjobs.Job](txish)
+	}
+	var result []*mvpjobs.Job
+	for c.Next() {
+		j := c.Row()
+		if status != mvpjobs.StatusNone && j.Status != status {
+			continue
+		}
+		result = append(result, j)
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result
+}
+
+// findJobForCommand looks up a job by ID, or by kind and optional name.
+func findJobForCommand(rc *RC, args []string) (*mvpjobs.Job, error) {
+	app := rc.app
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("expected <kind> [<name>] or <id>")
+	}
+	if kind := app.JobSchema.KindByName(args[0]); kind != nil {
+		var name string
+		if len(args) > 1 {
+			name = args[1]
+		}
+		j := app.Job(rc, kind, name)
+		if j == nil {
+			return nil, fmt.Errorf("job %s not found", kind.KindName(name))
+		}
+		return j, nil
+	}
+	if len(args) == 1 {
+		if id, err := flake.Parse(args[0]); err == nil {
+			if j := edb.Get[mvpjobs.Job](rc, id); j != nil {
+				return j, nil
+			}
+			return nil, fmt.Errorf("job %v not found", id)
+		}
+	}
+	return nil, fmt.Errorf("unknown job kind %q", args[0])
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
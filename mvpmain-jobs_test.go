@@ -0,0 +1,82 @@
+package mvp_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/mvp"
+	"github.com/andreyvit/mvp/backoff"
+	"github.com/andreyvit/mvp/mvpjobs"
+)
+
+func TestJobsCommand_retry(t *testing.T) {
+	var kind *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		kind = scm.Define("Broken", func(rc *mvp.RC, in *testJobParams) error {
+			return errors.New("boom")
+		}, mvpjobs.Idempotent, backoff.Backoff{FixedDelayRetries: 1, FixedDelay: time.Minute})
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, kind, &testJobParams{Name: "a"})
+	})
+	h.Advance(time.Hour)
+	h.ExpectStatus(kind, "a", mvpjobs.StatusFailed, 2)
+
+	if err := mvp.RunCommand(h.Ctx, h.App, []string{"jobs", "retry", "Broken", "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if j := h.Job(kind, "a"); j.Status != mvpjobs.StatusQueued || j.ConsecFailures != 0 {
+		t.Errorf("** after retry: %v with %d consecutive failures, wanted queued with 0", j.Status, j.ConsecFailures)
+	}
+	h.RunDue()
+	h.ExpectStatus(kind, "a", mvpjobs.StatusRetrying, 3) // backoff starts over
+}
+
+func TestJobsCommand(t *testing.T) {
+	var values []int
+	var sync, ping *mvpjobs.Kind
+	h := newTestApp(t, func(scm *mvpjobs.Schema) {
+		sync = scm.Define("Sync", func(rc *mvp.RC, in *testJobParams) error {
+			values = append(values, in.Value)
+			return nil
+		}, mvpjobs.Repeatable)
+		ping = scm.Define("Ping", nil, mvpjobs.Idempotent, mvpjobs.Ephemeral)
+	})
+	mustWrite(h, func(rc *mvp.RC) {
+		h.App.Enqueue(rc, sync, &testJobParams{Name: "a", Value: 1})
+	})
+	h.RunDue()
+	h.ExpectStatus(sync, "a", mvpjobs.StatusDone, 1)
+
+	tests := []struct {
+		args string
+		err  string
+	}{
+		{"jobs reenqueue Sync a", ""},
+		{`jobs run Sync {"value":2}`, ""},
+		{"jobs run Ping", "Ping is an ephemeral job kind, only persistent ones can be run"},
+		{"jobs reenqueue Nope a", `unknown job kind "Nope"`},
+		{"jobs frobnicate", `unknown jobs subcommand "frobnicate"`},
+	}
+	for _, tt := range tests {
+		err := mvp.RunCommand(h.Ctx, h.App, strings.Fields(tt.args))
+		if tt.err == "" && err != nil {
+			t.Errorf("** %s: %v", tt.args, err)
+		} else if tt.err != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.err)) {
+			t.Errorf("** %s: error %v, wanted %s", tt.args, err, tt.err)
+		}
+	}
+	h.ExpectStatus(sync, "a", mvpjobs.StatusQueued, 1)
+	h.RunDue()
+	h.ExpectStatus(sync, "a", mvpjobs.StatusDone, 2)
+	if a, e := values, []int{1, 2, 1}; !slices.Equal(a, e) {
+		t.Errorf("** handler calls = %v, wanted %v", a, e)
+	}
+
+	if err := h.App.RunJob(h.Ctx, ping, nil); err == nil {
+		t.Errorf("** RunJob of an ephemeral kind succeeded")
+	}
+}
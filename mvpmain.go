@@ -61,11 +61,14 @@ func Main(ge *Configuration) {
 	)
 	flag.Usage = func() {
 		base := filepath.Base(os.Args[0])
-		fmt.Printf("Usage: %s [options]\n\n", base)
+		fmt.Printf("Usage: %s [options] [command]\n\n", base)
 
 		fmt.Printf("Options:\n")
 		flag.PrintDefaults()
 
+		fmt.Printf("\n%s", jobsCommandUsage)
+		fmt.Printf("\n%s", otherCommandsUsage)
+
 		fmt.Printf("\nMost options are set in %s.\n", ge.ConfigFileName)
 	}
 
@@ -108,6 +111,15 @@ func Main(ge *Configuration) {
 	app.Initialize(settings, AppOptions{})
 	defer app.Close()
 
+	if args := flag.Args(); len(args) > 0 {
+		err := runCommand(ctx, app, args)
+		if err != nil {
+			app.Close()
+			log.Fatalf("** %v", err)
+		}
+		return
+	}
+
 	ensure(dir.Start(ctx, &director.Component{
 		Name:         "http",
 		Critical:     true,
@@ -126,14 +138,21 @@ func Main(ge *Configuration) {
 		return err
 	}))
 
-	if settings.WorkerCount > 0 {
+	if settings.WorkerCount > 0 || len(settings.WorkerSets) > 0 {
 		ensure(dir.Start(ctx, &director.Component{
 			Name:         "jobs",
 			Critical:     true,
 			RestartDelay: time.Second,
 		}, func(ctx context.Context, quitf func(err error)) error {
 			app.StartJobWorkers(ctx, settings.WorkerCount, quitf)
-			log.Printf("%v: %d persistent job workers started.", settings.AppName, settings.WorkerCount)
+			total := settings.WorkerCount
+			for _, n := range settings.WorkerSets {
+				total += n
+			}
+			log.Printf("%v: %d persistent job workers started.", settings.AppName, total)
+			for set, n := range settings.WorkerSets {
+				log.Printf("%v: %d persistent job workers started for set %s.", settings.AppName, n, set)
+			}
 			return nil
 		}))
 	}
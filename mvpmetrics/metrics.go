@@ -2,7 +2,11 @@ package mvpmetrics
 
 import (
 	"fmt"
+	"math"
+	"slices"
+	"strconv"
 	"sync/atomic"
+	"time"
 )
 
 type (
@@ -145,3 +149,98 @@ func (m *Gauge) Add(delta int64, labelValues ...string) {
 	atomic.AddInt64(v, delta)
 	m.values.release(meta)
 }
+
+type Buckets []float64
+
+// DefaultDurationBuckets are upper bounds in seconds suitable for durations
+// ranging from milliseconds to hours.
+var DefaultDurationBuckets = Buckets{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 3600}
+
+type Histogram struct {
+	desc
+	buckets []float64
+	values  vector[*histogramValue]
+}
+
+type histogramValue struct {
+	counts  []uint64 // non-cumulative, last one is +Inf
+	count   uint64
+	sumBits uint64
+}
+
+func NewHistogram(name string, labelNames []string, opts ...any) *Histogram {
+	m := &Histogram{
+		desc: desc{
+			name:       name,
+			labelNames: labelNames,
+		},
+		buckets: DefaultDurationBuckets,
+	}
+	reg := DefaultRegistry
+	for _, opt := range opts {
+		switch opt := opt.(type) {
+		case Help:
+			m.help = string(opt)
+		case Scale:
+			m.scale = float64(opt)
+		case Buckets:
+			if !slices.IsSorted(opt) {
+				panic(fmt.Errorf("%s: buckets must be sorted", name))
+			}
+			m.buckets = opt
+		case *Registry:
+			reg = opt
+		default:
+			panic(fmt.Errorf("invalid option %T %v", opt, opt))
+		}
+	}
+	reg.Add(m)
+	return m
+}
+
+func (m *Histogram) WriteMetricTo(mw *Writer) {
+	bucketName := m.name + "_bucket"
+	labelNames := append(slices.Clip(m.labelNames), "le")
+	m.values.enum(func(labelValues []string, hv *histogramValue) {
+		bucketLabels := append(slices.Clip(labelValues), "")
+		var cum uint64
+		for i, le := range m.buckets {
+			cum += atomic.LoadUint64(&hv.counts[i])
+			bucketLabels[len(bucketLabels)-1] = strconv.FormatFloat(le, 'f', -1, 64)
+			mw.WriteUint(bucketName, labelNames, bucketLabels, cum)
+		}
+		count := atomic.LoadUint64(&hv.count)
+		bucketLabels[len(bucketLabels)-1] = "+Inf"
+		mw.WriteUint(bucketName, labelNames, bucketLabels, count)
+		mw.WriteFloat(m.name+"_sum", m.labelNames, labelValues, math.Float64frombits(atomic.LoadUint64(&hv.sumBits)))
+		mw.WriteUint(m.name+"_count", m.labelNames, labelValues, count)
+	})
+}
+
+func (m *Histogram) Observe(value float64, labelValues ...string) {
+	m.desc.verifyCorrectLabels(labelValues)
+	if m.scale != 0 {
+		value /= m.scale
+	}
+	i, _ := slices.BinarySearch(m.buckets, value)
+
+	p, meta := m.values.acquire(labelValues)
+	if *p == nil {
+		*p = &histogramValue{counts: make([]uint64, len(m.buckets)+1)}
+	}
+	hv := *p
+	m.values.release(meta)
+
+	atomic.AddUint64(&hv.counts[i], 1)
+	for {
+		old := atomic.LoadUint64(&hv.sumBits)
+		if atomic.CompareAndSwapUint64(&hv.sumBits, old, math.Float64bits(math.Float64frombits(old)+value)) {
+			break
+		}
+	}
+	atomic.AddUint64(&hv.count, 1)
+}
+
+func (m *Histogram) ObserveDuration(d time.Duration, labelValues ...string) {
+	m.Observe(d.Seconds(), labelValues...)
+}
@@ -0,0 +1,248 @@
+package mvpopenapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/andreyvit/mvp/flake"
+	"github.com/andreyvit/mvp/jsonext"
+)
+
+const (
+	schemaRefPrefix = "#/components/schemas/"
+	jsonextPkgPath  = "github.com/andreyvit/mvp/jsonext"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	rawMessageType    = reflect.TypeOf(json.RawMessage(nil))
+	flakeIDType       = reflect.TypeOf(flake.ID(0))
+	optTimeType       = reflect.TypeOf(jsonext.OptTime{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	invalidNameCharsRe = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+)
+
+// Generator derives schemas from Go types, collecting named struct types
+// into Schemas so that they can be referenced and can be recursive.
+type Generator struct {
+	Schemas map[string]*Schema
+
+	names map[reflect.Type]string
+}
+
+func NewGenerator() *Generator {
+	return &Generator{
+		Schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// Schema returns the schema of values of type t, which is a reference for
+// named struct types.
+func (g *Generator) Schema(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case optTimeType:
+		return &Schema{Type: "string", Format: "date-time", Nullable: true}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "nanoseconds"}
+	case rawMessageType:
+		return &Schema{}
+	case flakeIDType:
+		return &Schema{Type: "string", Format: "flake-id", Nullable: true}
+	}
+	if t.PkgPath() == jsonextPkgPath && strings.HasPrefix(t.Name(), "Opt[") {
+		return nullable(g.Schema(t.Field(1).Type)) // Value
+	}
+
+	if t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface && (t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType)) {
+		return &Schema{} // unknown custom encoding
+	}
+	if t.Implements(textMarshalerType) || (t.Kind() != reflect.Ptr && reflect.PointerTo(t).Implements(textMarshalerType)) {
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Ptr:
+		return nullable(g.Schema(t.Elem()))
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.Schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.Schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return g.ref(g.nameFor(t), t)
+	default:
+		panic(fmt.Errorf("mvpopenapi: cannot describe %v", t))
+	}
+}
+
+// NamedSchema is like Schema, but registers t under the given name instead
+// of the type's own one.
+func (g *Generator) NamedSchema(name string, t reflect.Type) *Schema {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Errorf("mvpopenapi: NamedSchema requires a struct, got %v", t))
+	}
+	if existing, ok := g.names[t]; ok && existing != name {
+		panic(fmt.Errorf("mvpopenapi: %v is already named %s", t, existing))
+	}
+	if g.Schemas[name] != nil && g.names[t] != name {
+		panic(fmt.Errorf("mvpopenapi: schema name %s is already taken", name))
+	}
+	g.names[t] = name
+	return g.ref(name, t)
+}
+
+// Resolve returns the schema a reference points to, or s itself if it is
+// not a reference.
+func (g *Generator) Resolve(s *Schema) *Schema {
+	if name, ok := strings.CutPrefix(s.Ref, schemaRefPrefix); ok {
+		return g.Schemas[name]
+	}
+	return s
+}
+
+// nullable makes s accept null. Siblings of $ref are ignored, so
+// a reference gets wrapped into allOf.
+func nullable(s *Schema) *Schema {
+	if s.Ref != "" {
+		return &Schema{AllOf: []*Schema{s}, Nullable: true}
+	}
+	s.Nullable = true
+	return s
+}
+
+func (g *Generator) ref(name string, t reflect.Type) *Schema {
+	if g.Schemas[name] == nil {
+		g.Schemas[name] = &Schema{Type: "object"} // placeholder for recursive types
+		*g.Schemas[name] = *g.structSchema(t)
+	}
+	return &Schema{Ref: schemaRefPrefix + name}
+}
+
+func (g *Generator) nameFor(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := invalidNameCharsRe.ReplaceAllString(t.Name(), "_")
+	if g.isNameTaken(name) {
+		name = path.Base(t.PkgPath()) + "_" + name
+	}
+	for base, i := name, 2; g.isNameTaken(name); i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	g.names[t] = name
+	return name
+}
+
+func (g *Generator) isNameTaken(name string) bool {
+	if g.Schemas[name] != nil {
+		return true
+	}
+	for _, n := range g.names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// structSchema lists the fields of a struct as encoding/json would encode
+// them. Fields without omitempty are required, since they're always emitted.
+func (g *Generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(s, t)
+	return s
+}
+
+func (g *Generator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addFields(s, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		var fs *Schema
+		if hasTagOption(opts, "string") && isStringableKind(f.Type.Kind()) {
+			fs = &Schema{Type: "string"}
+		} else {
+			fs = g.Schema(f.Type)
+		}
+		s.Properties[name] = fs
+		if !hasTagOption(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+func hasTagOption(opts, opt string) bool {
+	for opts != "" {
+		var o string
+		o, opts, _ = strings.Cut(opts, ",")
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+func isStringableKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.String:
+		return true
+	default:
+		return false
+	}
+}
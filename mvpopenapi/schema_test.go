@@ -0,0 +1,81 @@
+package mvpopenapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/andreyvit/mvp/flake"
+	"github.com/andreyvit/mvp/jsonext"
+)
+
+type testBase struct {
+	ID      flake.ID  `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+type testNode struct {
+	testBase
+	Name     string           `json:"name"`
+	Note     *string          `json:"note,omitempty"`
+	Count    int64            `json:"count,string"`
+	Timeout  jsonext.Duration `json:"timeout"`
+	Limit    jsonext.Opt[int] `json:"limit"`
+	Children []*testNode      `json:"children,omitempty"`
+	Parent   *testNode        `json:"parent"`
+	Extra    map[string]any   `json:"extra,omitempty"`
+	Data     []byte           `json:"data,omitempty"`
+	Internal string           `json:"-"`
+	hidden   string
+}
+
+func TestSchema(t *testing.T) {
+	g := NewGenerator()
+	s := g.Schema(reflect.TypeOf(testNode{}))
+	if a, e := s.Ref, "#/components/schemas/testNode"; a != e {
+		t.Fatalf("Ref = %q, wanted %q", a, e)
+	}
+
+	a := string(must(json.Marshal(g.Schemas)))
+	e := `{"testNode":{"type":"object","properties":{` +
+		`"children":{"type":"array","items":{"nullable":true,"allOf":[{"$ref":"#/components/schemas/testNode"}]}},` +
+		`"count":{"type":"string"},` +
+		`"created":{"type":"string","format":"date-time"},` +
+		`"data":{"type":"string","format":"byte"},` +
+		`"extra":{"type":"object","additionalProperties":{}},` +
+		`"id":{"type":"string","format":"flake-id","nullable":true},` +
+		`"limit":{"type":"integer","format":"int64","nullable":true},` +
+		`"name":{"type":"string"},` +
+		`"note":{"type":"string","nullable":true},` +
+		`"parent":{"nullable":true,"allOf":[{"$ref":"#/components/schemas/testNode"}]},` +
+		`"timeout":{"type":"string"}` +
+		`},"required":["id","created","name","count","timeout","limit","parent"]}}`
+	if a != e {
+		t.Errorf("got:\n%s\nwanted:\n%s", a, e)
+	}
+}
+
+func TestSchemaNameConflicts(t *testing.T) {
+	g := NewGenerator()
+	g.Schema(reflect.TypeOf(testBase{}))
+
+	type testBase struct {
+		Other bool `json:"other"`
+	}
+	s := g.Schema(reflect.TypeOf(testBase{}))
+	if a, e := s.Ref, "#/components/schemas/mvpopenapi_testBase"; a != e {
+		t.Errorf("Ref = %q, wanted %q", a, e)
+	}
+	s = g.NamedSchema("Base", reflect.TypeOf(&struct{ X int }{}))
+	if a, e := g.Resolve(s).Properties["X"].Type, "integer"; a != e {
+		t.Errorf("Resolve(NamedSchema).X = %q, wanted %q", a, e)
+	}
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
@@ -3,6 +3,7 @@ package mvprpc
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/andreyvit/mvp/expandable"
@@ -77,3 +78,15 @@ func (api *API) Method(name string, in any, out any, opts ...any) *Method {
 
 	return meth
 }
+
+// Methods returns the methods of the API sorted by name.
+func (api *API) Methods() []*Method {
+	result := make([]*Method, 0, len(api.methodsByName))
+	for _, m := range api.methodsByName {
+		result = append(result, m)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
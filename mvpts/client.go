@@ -0,0 +1,263 @@
+// Package mvpts generates TypeScript clients from OpenAPI schemas produced
+// by mvpopenapi, so that TypeScript types follow the JSON encoding of Go types.
+package mvpts
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/andreyvit/mvp/mvpopenapi"
+)
+
+const schemaRefPrefix = "#/components/schemas/"
+
+var (
+	identRe    = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+	nonAlnumRe = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+// Method is an RPC method of the client. Result is nil for methods that
+// return nothing.
+type Method struct {
+	Name   string
+	Params *mvpopenapi.Schema
+	Result *mvpopenapi.Schema
+}
+
+// Client describes a TypeScript client: an interface or type alias for each
+// of Schemas, and a Client class with a method for each of Methods.
+type Client struct {
+	Schemas map[string]*mvpopenapi.Schema
+	Methods []*Method
+}
+
+// Generate returns the TypeScript source of the client. Panics if two
+// methods map to the same TypeScript name, or one clashes with a member
+// of the Client class itself.
+func (c *Client) Generate() []byte {
+	var buf strings.Builder
+	buf.WriteString("// Code generated by mvp; DO NOT EDIT.\n")
+
+	names := make([]string, 0, len(c.Schemas))
+	for name := range c.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := c.Schemas[name]
+		buf.WriteString("\n")
+		if s.Description != "" {
+			fmt.Fprintf(&buf, "// %s\n", s.Description)
+		}
+		if s.Type == "object" && s.AdditionalProperties == nil {
+			fmt.Fprintf(&buf, "export interface %s %s\n", name, objectType(s, ""))
+		} else if members := unionMembers(s); len(members) > 1 && !s.Nullable {
+			fmt.Fprintf(&buf, "export type %s =\n", name)
+			for i, m := range members {
+				fmt.Fprintf(&buf, "  | %s", m)
+				if i == len(members)-1 {
+					buf.WriteString(";")
+				}
+				buf.WriteString("\n")
+			}
+		} else {
+			fmt.Fprintf(&buf, "export type %s = %s;\n", name, Type(s))
+		}
+	}
+
+	buf.WriteString("\n// Transport performs an RPC call, resolving with its result.\n")
+	buf.WriteString("export type Transport = (method: string, params: unknown) => Promise<unknown>;\n")
+	buf.WriteString("\nexport class Client {\n")
+	buf.WriteString("  constructor(private readonly transport: Transport) {}\n")
+	methodNames := map[string]string{"constructor": "", "transport": ""}
+	for _, m := range c.Methods {
+		name := MethodName(m.Name)
+		if other, taken := methodNames[name]; taken {
+			if other == "" {
+				panic(fmt.Errorf("mvpts: method %s clashes with Client.%s", m.Name, name))
+			}
+			panic(fmt.Errorf("mvpts: methods %s and %s both map to Client.%s", other, m.Name, name))
+		}
+		methodNames[name] = m.Name
+	}
+	for _, m := range c.Methods {
+		result := "void"
+		if m.Result != nil {
+			result = Type(m.Result)
+		}
+		buf.WriteString("\n")
+		if c.hasParams(m.Params) {
+			fmt.Fprintf(&buf, "  %s(params: %s): Promise<%s> {\n", MethodName(m.Name), indentedType(m.Params, "  "), result)
+			fmt.Fprintf(&buf, "    return this.transport(%s, params) as Promise<%s>;\n", strconv.Quote(m.Name), result)
+		} else {
+			fmt.Fprintf(&buf, "  %s(): Promise<%s> {\n", MethodName(m.Name), result)
+			fmt.Fprintf(&buf, "    return this.transport(%s, {}) as Promise<%s>;\n", strconv.Quote(m.Name), result)
+		}
+		buf.WriteString("  }\n")
+	}
+	buf.WriteString("}\n")
+	return []byte(buf.String())
+}
+
+func (c *Client) hasParams(s *mvpopenapi.Schema) bool {
+	if name, ok := strings.CutPrefix(s.Ref, schemaRefPrefix); ok {
+		s = c.Schemas[name]
+	}
+	return s == nil || s.Type != "object" || s.AdditionalProperties != nil || len(s.Properties) > 0
+}
+
+// Type returns the TypeScript type expression for the given schema.
+func Type(s *mvpopenapi.Schema) string {
+	t := baseType(s)
+	if s.Nullable {
+		t += " | null"
+	}
+	return t
+}
+
+func baseType(s *mvpopenapi.Schema) string {
+	if name, ok := strings.CutPrefix(s.Ref, schemaRefPrefix); ok {
+		return name
+	}
+	if len(s.AllOf) == 1 {
+		return Type(s.AllOf[0])
+	}
+	if members := unionMembers(s); members != nil {
+		return strings.Join(members, " | ")
+	}
+	switch s.Type {
+	case "string":
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		item := Type(s.Items)
+		if strings.Contains(item, " ") {
+			return "(" + item + ")[]"
+		}
+		return item + "[]"
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "Record<string, " + Type(s.AdditionalProperties) + ">"
+		}
+		return objectType(s, "")
+	default:
+		return "unknown"
+	}
+}
+
+// unionMembers returns the members of the union type for an enum or anyOf
+// schema, or nil for other schemas. A plain string alongside string literals
+// becomes (string & {}), so that TypeScript keeps the literals of an
+// open-ended set instead of collapsing the union into string.
+func unionMembers(s *mvpopenapi.Schema) []string {
+	if len(s.Enum) > 0 {
+		members := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			members[i] = literal(v)
+		}
+		return members
+	}
+	if len(s.AnyOf) == 0 {
+		return nil
+	}
+	var hasLiterals bool
+	for _, sub := range s.AnyOf {
+		if len(sub.Enum) > 0 {
+			hasLiterals = true
+		}
+	}
+	var members []string
+	for _, sub := range s.AnyOf {
+		if sub.Nullable {
+			members = append(members, "("+Type(sub)+")")
+		} else if hasLiterals && sub.Ref == "" && sub.Type == "string" && len(sub.Enum) == 0 {
+			members = append(members, "(string & {})")
+		} else if m := unionMembers(sub); m != nil {
+			members = append(members, m...)
+		} else {
+			members = append(members, Type(sub))
+		}
+	}
+	return members
+}
+
+func objectType(s *mvpopenapi.Schema, indent string) string {
+	if len(s.Properties) == 0 {
+		return "{}"
+	}
+	required := make(map[string]bool, len(s.Required))
+	for _, name := range s.Required {
+		required[name] = true
+	}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf strings.Builder
+	buf.WriteString("{\n")
+	for _, name := range names {
+		ps := s.Properties[name]
+		key := name
+		if !identRe.MatchString(key) {
+			key = strconv.Quote(key)
+		}
+		if !required[name] {
+			key += "?"
+		}
+		fmt.Fprintf(&buf, "%s  %s: %s;\n", indent, key, indentedType(ps, indent+"  "))
+	}
+	buf.WriteString(indent + "}")
+	return buf.String()
+}
+
+// indentedType is like Type, but indents inline object types.
+func indentedType(s *mvpopenapi.Schema, indent string) string {
+	if s.Ref == "" && s.Type == "object" && s.AdditionalProperties == nil && len(s.Enum) == 0 {
+		t := objectType(s, indent)
+		if s.Nullable {
+			t += " | null"
+		}
+		return t
+	}
+	return Type(s)
+}
+
+func literal(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// MethodName turns an RPC method name like users.get or GetUser into
+// a camelCase TypeScript identifier.
+func MethodName(name string) string {
+	var buf strings.Builder
+	for _, part := range nonAlnumRe.Split(name, -1) {
+		if part == "" {
+			continue
+		}
+		r := []rune(part)
+		if buf.Len() == 0 {
+			r[0] = unicode.ToLower(r[0])
+		} else {
+			r[0] = unicode.ToUpper(r[0])
+		}
+		buf.WriteString(string(r))
+	}
+	if buf.Len() == 0 || !identRe.MatchString(buf.String()) {
+		return "_" + buf.String()
+	}
+	return buf.String()
+}
@@ -0,0 +1,122 @@
+package mvpts
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/andreyvit/mvp/mvpopenapi"
+)
+
+func TestGenerate(t *testing.T) {
+	c := &Client{
+		Schemas: map[string]*mvpopenapi.Schema{
+			"Kind": {Type: "string", Enum: []any{"a", "b"}},
+			"Code": {Type: "string", Description: "Code, such as x or y"},
+			"Open": {AnyOf: []*mvpopenapi.Schema{{Type: "string", Enum: []any{"x", "y"}}, {Type: "string"}}},
+			"Item": {
+				Type: "object",
+				Properties: map[string]*mvpopenapi.Schema{
+					"id":       {Type: "string", Nullable: true},
+					"kind":     {Ref: schemaRefPrefix + "Kind"},
+					"parent":   {AllOf: []*mvpopenapi.Schema{{Ref: schemaRefPrefix + "Item"}}, Nullable: true},
+					"tags":     {Type: "array", Items: &mvpopenapi.Schema{Type: "string", Nullable: true}},
+					"attrs":    {Type: "object", AdditionalProperties: &mvpopenapi.Schema{Type: "integer"}},
+					"x-extra":  {},
+					"position": {Type: "object", Properties: map[string]*mvpopenapi.Schema{"x": {Type: "number"}}, Required: []string{"x"}},
+				},
+				Required: []string{"id", "kind", "tags"},
+			},
+		},
+		Methods: []*Method{
+			{Name: "items.get", Params: &mvpopenapi.Schema{Type: "object", Properties: map[string]*mvpopenapi.Schema{"id": {Type: "string"}}}, Result: &mvpopenapi.Schema{Ref: schemaRefPrefix + "Item"}},
+			{Name: "Ping", Params: &mvpopenapi.Schema{Type: "object", Properties: map[string]*mvpopenapi.Schema{}}},
+		},
+	}
+	a := string(c.Generate())
+	e := `// Code generated by mvp; DO NOT EDIT.
+
+// Code, such as x or y
+export type Code = string;
+
+export interface Item {
+  attrs?: Record<string, number>;
+  id: string | null;
+  kind: Kind;
+  parent?: Item | null;
+  position?: {
+    x: number;
+  };
+  tags: (string | null)[];
+  "x-extra"?: unknown;
+}
+
+export type Kind =
+  | "a"
+  | "b";
+
+export type Open =
+  | "x"
+  | "y"
+  | (string & {});
+
+// Transport performs an RPC call, resolving with its result.
+export type Transport = (method: string, params: unknown) => Promise<unknown>;
+
+export class Client {
+  constructor(private readonly transport: Transport) {}
+
+  itemsGet(params: {
+    id?: string;
+  }): Promise<Item> {
+    return this.transport("items.get", params) as Promise<Item>;
+  }
+
+  ping(): Promise<void> {
+    return this.transport("Ping", {}) as Promise<void>;
+  }
+}
+`
+	if a != e {
+		t.Errorf("got:\n%s\nwanted:\n%s", a, e)
+	}
+}
+
+func TestMethodName(t *testing.T) {
+	tests := []struct {
+		name, expected string
+	}{
+		{"GetUser", "getUser"},
+		{"users.get_all", "usersGetAll"},
+		{"2fa.enable", "_2faEnable"},
+	}
+	for _, tt := range tests {
+		if a := MethodName(tt.name); a != tt.expected {
+			t.Errorf("MethodName(%q) = %q, wanted %q", tt.name, a, tt.expected)
+		}
+	}
+}
+
+func TestGenerate_nameClashes(t *testing.T) {
+	tests := []struct {
+		names    []string
+		expected string
+	}{
+		{[]string{"users.get", "usersGet"}, "mvpts: methods users.get and usersGet both map to Client.usersGet"},
+		{[]string{"Constructor"}, "mvpts: method Constructor clashes with Client.constructor"},
+		{[]string{"transport"}, "mvpts: method transport clashes with Client.transport"},
+	}
+	for _, tt := range tests {
+		c := &Client{}
+		for _, name := range tt.names {
+			c.Methods = append(c.Methods, &Method{Name: name, Params: &mvpopenapi.Schema{Type: "object"}})
+		}
+		func() {
+			defer func() {
+				if a := fmt.Sprint(recover()); a != tt.expected {
+					t.Errorf("Generate(%q) panic = %q, wanted %q", tt.names, a, tt.expected)
+				}
+			}()
+			c.Generate()
+		}()
+	}
+}
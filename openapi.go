@@ -0,0 +1,196 @@
+package mvp
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/andreyvit/mvp/hotwired"
+	"github.com/andreyvit/mvp/httperrors"
+	"github.com/andreyvit/mvp/mvpopenapi"
+	"golang.org/x/exp/maps"
+)
+
+const openAPIErrorResponseRef = "#/components/responses/Error"
+
+// nonJSONOutputTypes lists handler output types that writeResponse doesn't
+// encode as JSON, with their content types if known.
+var nonJSONOutputTypes = map[reflect.Type]string{
+	reflect.TypeOf((*ViewData)(nil)):        "text/html",
+	reflect.TypeOf((*RawOutput)(nil)):       "",
+	reflect.TypeOf((*hotwired.Stream)(nil)): hotwired.StreamContentType,
+	reflect.TypeOf(ResponseHandled{}):       "",
+}
+
+// OpenAPIRoute adds a route serving the OpenAPI document of the app at the
+// given path.
+func OpenAPIRoute(b *RouteBuilder, path string) *Route {
+	return b.Route("mvp.openapi", "GET "+path, func(rc *RC, in *struct{}) (*mvpopenapi.Document, error) {
+		return rc.app.OpenAPI(), nil
+	})
+}
+
+// OpenAPI describes the routes of the app, and the mvprpc methods implemented
+// via MethodImpl (except for job methods) under the x-rpc-methods extension.
+// Schemas are derived from the input and output types of the handlers.
+// Errors have the shape produced by BuildAPIErrorResponse; IDs of errors
+// created via httperrors.Define are listed, but other IDs are possible.
+func (app *App) OpenAPI() *mvpopenapi.Document {
+	g := mvpopenapi.NewGenerator()
+	doc := &mvpopenapi.Document{
+		OpenAPI: mvpopenapi.Version,
+		Info: mvpopenapi.Info{
+			Title:   app.Settings.AppName,
+			Version: "dev",
+		},
+		Paths: make(map[string]mvpopenapi.PathItem),
+		Components: mvpopenapi.Components{
+			Responses: map[string]*mvpopenapi.Response{
+				"Error": openAPIErrorResponse(g),
+			},
+		},
+	}
+	if app.Configuration != nil && app.Configuration.BuildVer != "" {
+		doc.Info.Version = app.Configuration.BuildVer
+	}
+	if app.BaseURL != nil {
+		doc.Servers = []*mvpopenapi.Server{{URL: strings.TrimSuffix(app.BaseURL.String(), "/")}}
+	}
+
+	routes := maps.Values(app.routesByName)
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].routeName < routes[j].routeName
+	})
+	for _, route := range routes {
+		path := pathParamsRe.ReplaceAllString(route.path, "{$1}")
+		item := doc.Paths[path]
+		if item == nil {
+			item = make(mvpopenapi.PathItem)
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(route.method)] = openAPIOperation(g, route)
+	}
+
+	for _, m := range app.rpcMethodImpls() {
+		rm := &mvpopenapi.RPCMethod{Params: g.Schema(m.InType)}
+		if m.OutType != nil {
+			rm.Result = resultSchema(g, m.OutType)
+		}
+		if doc.RPCMethods == nil {
+			doc.RPCMethods = make(map[string]*mvpopenapi.RPCMethod)
+		}
+		doc.RPCMethods[m.Name] = rm
+	}
+
+	doc.Components.Schemas = g.Schemas
+	return doc
+}
+
+func openAPIOperation(g *mvpopenapi.Generator, route *Route) *mvpopenapi.Operation {
+	op := &mvpopenapi.Operation{
+		OperationID: route.routeName,
+		Responses: map[string]*mvpopenapi.Response{
+			"default": {Ref: openAPIErrorResponseRef},
+		},
+	}
+
+	isPathParam := make(map[string]bool)
+	for _, name := range route.pathParams {
+		isPathParam[name] = true
+		op.Parameters = append(op.Parameters, &mvpopenapi.Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &mvpopenapi.Schema{Type: "string"},
+		})
+	}
+
+	in := g.Schema(route.inType)
+	props := g.Resolve(in).Properties
+	if route.method == http.MethodGet || route.method == http.MethodHead {
+		names := maps.Keys(props)
+		sort.Strings(names)
+		for _, name := range names {
+			if isPathParam[name] {
+				continue
+			}
+			op.Parameters = append(op.Parameters, &mvpopenapi.Parameter{
+				Name:   name,
+				In:     "query",
+				Schema: props[name],
+			})
+		}
+	} else if len(props) > 0 {
+		op.RequestBody = &mvpopenapi.RequestBody{
+			Content: mvpopenapi.JSONContent(in),
+		}
+	}
+
+	t := route.outType
+	if t == reflect.TypeOf((*Redirect)(nil)) {
+		op.Responses["3XX"] = &mvpopenapi.Response{Description: "Redirect"}
+	} else if contentType, ok := nonJSONOutputTypes[t]; ok {
+		resp := &mvpopenapi.Response{Description: "OK"}
+		if contentType != "" {
+			resp.Content = map[string]*mvpopenapi.MediaType{contentType: {}}
+		}
+		if route.htmlOrJSON && t == reflect.TypeOf((*ViewData)(nil)) {
+			resp.Content["application/json"] = &mvpopenapi.MediaType{Schema: &mvpopenapi.Schema{}}
+		}
+		op.Responses["200"] = resp
+	} else if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
+		op.Responses["200"] = &mvpopenapi.Response{
+			Description: "OK",
+			Content:     mvpopenapi.JSONContent(resultSchema(g, t)),
+		}
+	} else {
+		op.Responses["200"] = &mvpopenapi.Response{Description: "OK"}
+	}
+	return op
+}
+
+// resultSchema describes the output of a handler. Handlers return pointers,
+// but never nil ones on success, so the result is not nullable.
+func resultSchema(g *mvpopenapi.Generator, t reflect.Type) *mvpopenapi.Schema {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return g.Schema(t)
+}
+
+func openAPIErrorResponse(g *mvpopenapi.Generator) *mvpopenapi.Response {
+	var descs []string
+	for _, e := range httperrors.Defined() {
+		descs = append(descs, fmt.Sprintf("%s (HTTP %d)", e.ErrorID(), e.HTTPCode()))
+	}
+	return &mvpopenapi.Response{
+		Description: "Error, including but not limited to: " + strings.Join(descs, ", "),
+		Content:     mvpopenapi.JSONContent(apiErrorSchema(g, "Error")),
+	}
+}
+
+// apiErrorSchema registers the schema of BuildAPIErrorResponse under
+// the given name. IDs of errors created via httperrors.Define are listed in
+// the description rather than as an enum, because errors created on the fly
+// (like httperrors.Errorf) can have any ID.
+func apiErrorSchema(g *mvpopenapi.Generator, name string) *mvpopenapi.Schema {
+	schema := g.NamedSchema(name, reflect.TypeOf(defaultAPIErrorResponse{}))
+	g.Resolve(schema).Properties["error"].Description = "Error ID, not limited to the predefined ones: " + strings.Join(definedAPIErrorIDs(), ", ")
+	return schema
+}
+
+// definedAPIErrorIDs returns the unique IDs of errors created via
+// httperrors.Define.
+func definedAPIErrorIDs() []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, e := range httperrors.Defined() {
+		if !seen[e.ErrorID()] {
+			seen[e.ErrorID()] = true
+			ids = append(ids, e.ErrorID())
+		}
+	}
+	return ids
+}
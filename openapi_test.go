@@ -0,0 +1,18 @@
+package mvp_test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOpenAPI_errorIDs(t *testing.T) {
+	h := newTestApp(t, nil)
+	doc := h.App.OpenAPI()
+	errID := doc.Components.Schemas["Error"].Properties["error"]
+	if len(errID.Enum) > 0 {
+		t.Errorf("** error ID is a closed enum %v, but httperrors.Errorf can produce any ID", errID.Enum)
+	}
+	if !strings.Contains(errID.Description, "too_many_requests") {
+		t.Errorf("** error ID description does not list predefined IDs: %q", errID.Description)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/andreyvit/mvp/flake"
 	"github.com/andreyvit/mvp/flogger"
 	"github.com/andreyvit/mvp/httpcall"
+	"github.com/andreyvit/mvp/mvpjobs"
 	mvpm "github.com/andreyvit/mvp/mvpmodel"
 	"github.com/uptrace/bunrouter"
 )
@@ -80,8 +81,16 @@ type RC struct {
 	RateLimitPreset RateLimitPreset
 	RateLimitKey    string
 
+	job *mvpjobs.Job
+
 	extraLogger  func(format string, args ...any)
 	cacheBusting map[any]struct{}
+	jobsToCancel []mvpjobs.JobID
+	csrfToken    string
+	etag         string
+	lastModified time.Time
+
+	cacheValidatorsSeq uint64
 }
 
 type RCish interface {
@@ -117,7 +126,7 @@ func NewRC(ctx context.Context, app *App, requestID string) *RC {
 		app:       app,
 		RequestID: requestID,
 		Start:     time.Now(),
-		now:       time.Now(),
+		now:       app.Now(),
 	}
 	runHooksFwd2(app.Hooks.initRC, app, rc)
 	return rc
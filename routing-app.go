@@ -63,6 +63,20 @@ func initRouting(app *App) {
 	app.domainRouter = dr
 }
 
+// middlewareOutputAPIError returns the error to send to JSON clients of
+// an HTMLOrJSON route instead of the output of a middleware, like a redirect
+// to the login page, or nil if the output can be sent as JSON.
+func middlewareOutputAPIError(output any) error {
+	switch output := output.(type) {
+	case *ViewData, ResponseHandled:
+		return nil
+	case *Redirect:
+		return ErrAPIRedirected.Msg(output.EffectivePath())
+	default:
+		return ErrAPINotAcceptable.Msgf("%T cannot be sent as JSON", output)
+	}
+}
+
 // callRoute handles an HTTP request using the middleware, handler and parameters of the given route.
 //
 // Note: we might be doing too much here, some logic should probably be moved into middleware.
@@ -72,17 +86,21 @@ func (app *App) callRoute(route *Route, rc *RC, w http.ResponseWriter, req bunro
 		rc.RateLimitPreset = route.rateLimitPreset
 	}
 
-	if !route.idempotent {
-		// TODO: check CSRF
-	}
-
 	inVal := reflect.New(route.inType)
 	err := formConfig.DecodeVal(req.Request, req.Params(), inVal)
 	if err != nil {
 		return err
 	}
 
+	if route.csrfProtected && !route.idempotent {
+		err := rc.checkCSRF()
+		if err != nil {
+			return err
+		}
+	}
+
 	var output any
+	var outputFromMiddleware bool
 
 	err = rc.InTx(route.storeAffinity, func() error {
 		for _, mw := range route.middleware {
@@ -98,6 +116,7 @@ func (app *App) callRoute(route *Route, rc *RC, w http.ResponseWriter, req bunro
 				return err
 			}
 			if output != nil {
+				outputFromMiddleware = true
 				return nil
 			}
 		}
@@ -113,10 +132,18 @@ func (app *App) callRoute(route *Route, rc *RC, w http.ResponseWriter, req bunro
 		}
 		return nil
 	})
+	if err == nil && outputFromMiddleware && route.htmlOrJSON && mvputil.PreferJSON(req.Request) {
+		err = middlewareOutputAPIError(output)
+	}
 	if err != nil {
 		app.writeResponseExtras(rc, w, req.Request)
 		return err
 	}
 
+	if rc.applyCacheValidators(w, req.Request) {
+		app.writeResponseExtras(rc, w, req.Request)
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
 	return app.writeResponse(rc, output, w, req.Request)
 }
@@ -36,6 +36,18 @@ const (
 	ReadOnly RouteFlagOption = 1 + iota
 	Mutator
 	IdempotentMutator
+
+	// HTMLOrJSON makes a route that returns *ViewData respond with JSON
+	// to clients that prefer it (see mvputil.PreferJSON), serializing
+	// ViewData.APIData, or ViewData.Data if unset. Errors are then written
+	// via WriteAPIError as well, and so are redirects and other non-JSON
+	// responses of middleware (as ErrAPIRedirected and ErrAPINotAcceptable).
+	HTMLOrJSON
+
+	// CSRFProtected makes a non-idempotent route reject requests that do not
+	// carry the token returned by RC.CSRFToken, either as a _csrf form field
+	// or as X-CSRF-Token header.
+	CSRFProtected
 )
 
 const (
@@ -151,6 +163,7 @@ func (g *RouteBuilder) addRoute(routeName, method, path string, isIdempotentByDe
 		funcVal:        fv,
 		rcFacet:        rcFacet,
 		inType:         inTyp,
+		outType:        ft.Out(0),
 		idempotent:     isIdempotentByDefault,
 		routingContext: g.routingContext.clone(),
 	}
@@ -182,6 +195,10 @@ func (g *RouteBuilder) addRoute(routeName, method, path string, isIdempotentByDe
 				route.idempotent = false
 			case ReadOnly:
 				route.idempotent = true
+			case HTMLOrJSON:
+				route.htmlOrJSON = true
+			case CSRFProtected:
+				route.csrfProtected = true
 			}
 		default:
 			panic(fmt.Errorf("%s: invalid option %T %v", routeName, opt, opt))
@@ -215,11 +232,18 @@ func (g *RouteBuilder) addRoute(routeName, method, path string, isIdempotentByDe
 	handler := func(w http.ResponseWriter, req bunrouter.Request) error {
 		rc := g.app.NewHTTPRequestRC(w, req)
 		defer rc.Close()
+		if route.htmlOrJSON {
+			w.Header().Add("Vary", "Accept")
+		}
 
 		err := g.app.callRoute(route, rc, w, req)
 		logRequest(rc, req.Request, err)
 		if err != nil {
-			http.Error(w, err.Error(), httperrors.HTTPCode(err))
+			if route.htmlOrJSON && mvputil.PreferJSON(req.Request) {
+				g.app.WriteAPIError(w, err)
+			} else {
+				http.Error(w, err.Error(), httperrors.HTTPCode(err))
+			}
 		}
 		return nil
 	}
@@ -16,7 +16,10 @@ type Route struct {
 	funcVal        reflect.Value
 	rcFacet        expandable.Any[RC]
 	inType         reflect.Type
+	outType        reflect.Type
 	idempotent     bool
+	htmlOrJSON     bool
+	csrfProtected  bool
 	storeAffinity  mvpm.StoreAffinity
 	pathParams     []string
 	routingContext
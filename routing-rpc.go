@@ -3,6 +3,7 @@ package mvp
 import (
 	"fmt"
 	"reflect"
+	"sort"
 
 	"github.com/andreyvit/mvp/mvpjobs"
 	"github.com/andreyvit/mvp/mvprpc"
@@ -42,9 +43,15 @@ func (app *App) JobImpl(kind *mvpjobs.Kind) {
 		Enabled:        kind.Enabled,
 		Kind:           kind,
 		RepeatInterval: kind.RepeatInterval,
+		Schedule:       kind.Schedule,
+		RateLimiter:    app.jobRateLimiter(kind),
 	}
 	app.jobsByKind[kind] = ji
-	app.MethodImpl(kind.Method, kind.Handler)
+	if kind.BatchHandler != nil {
+		ji.callBatch = batchJobCall(kind)
+	} else {
+		app.MethodImpl(kind.Method, kind.Handler)
+	}
 }
 
 func (app *App) MethodImpl(method *mvprpc.Method, impl any) {
@@ -115,3 +122,40 @@ func (app *App) MethodImpl(method *mvprpc.Method, impl any) {
 	}
 	app.methodsByName[name] = m
 }
+
+// rpcMethodImpls returns the implemented methods of the given APIs, or all
+// implemented methods except for those of jobs if no APIs are given, sorted
+// by name.
+func (app *App) rpcMethodImpls(apis ...*mvprpc.API) []*MethodImpl {
+	var result []*MethodImpl
+	if len(apis) > 0 {
+		for _, api := range apis {
+			for _, method := range api.Methods() {
+				if m := app.methodsByName[method.Name]; m != nil && m.Method == method {
+					result = append(result, m)
+				}
+			}
+		}
+	} else {
+		for _, m := range app.methodsByName {
+			if !app.isJobMethod(m.Method) {
+				result = append(result, m)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// isJobMethod returns whether method is the method of a job kind, which
+// must not be called directly.
+func (app *App) isJobMethod(method *mvprpc.Method) bool {
+	for kind := range app.jobsByKind {
+		if kind.Method == method {
+			return true
+		}
+	}
+	return false
+}
@@ -49,8 +49,16 @@ func (app *App) WriteAPIError(w http.ResponseWriter, err error) {
 }
 
 func WriteAPIResponse(w http.ResponseWriter, out any, indented bool) {
+	writeAPIResponse(w, 0, out, indented)
+}
+
+// writeAPIResponse is WriteAPIResponse with a custom status code, unless 0.
+func writeAPIResponse(w http.ResponseWriter, statusCode int, out any, indented bool) {
 	mvphttp.ApplyCacheMode(w, mvphttp.Uncached)
 	w.Header().Set("Content-Type", "application/json")
+	if statusCode != 0 {
+		w.WriteHeader(statusCode)
+	}
 	var err error
 	if indented {
 		var data []byte
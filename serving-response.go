@@ -145,6 +145,17 @@ func (app *App) writeResponse(rc *RC, output any, w http.ResponseWriter, r *http
 	app.writeResponseExtras(rc, w, r)
 	switch output := output.(type) {
 	case *ViewData:
+		if rc.Route != nil && rc.Route.htmlOrJSON && mvputil.PreferJSON(r) {
+			data := output.APIData
+			if data == nil {
+				data = output.Data
+			}
+			if data == nil {
+				data = struct{}{}
+			}
+			writeAPIResponse(w, output.StatusCode, data, false)
+			break
+		}
 		if output.View == "" {
 			output.View = strings.ReplaceAll(rc.Route.routeName, ".", "-")
 		}
@@ -169,6 +180,14 @@ func (app *App) writeResponse(rc *RC, output any, w http.ResponseWriter, r *http
 	case *Redirect:
 		path := output.EffectivePath()
 		http.Redirect(w, r, path, output.EffectiveStatusCode())
+	case *RawOutput:
+		for k, v := range output.Header {
+			w.Header()[k] = v
+		}
+		if output.ContentType != "" {
+			w.Header().Set("Content-Type", output.ContentType)
+		}
+		w.Write(output.Data)
 	case DebugOutput:
 		w.Header().Set("Content-Type", "text/plain")
 		w.Write([]byte(output))
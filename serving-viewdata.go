@@ -15,6 +15,7 @@ type ViewData struct {
 	TitleVisible bool
 	Layout       string
 	Data         any
+	APIData      any // sent instead of Data to JSON clients of HTMLOrJSON routes
 	SemanticPath string
 	StatusCode   int
 	Flash        *Flash
@@ -2,6 +2,7 @@ package mvp
 
 import (
 	"bytes"
+	"embed"
 	"fmt"
 	"html/template"
 	"io"
@@ -15,6 +16,13 @@ import (
 	"github.com/andreyvit/mvp/flogger"
 )
 
+// builtinViewsFS holds the views of the pages provided by mvp itself, like
+// the job dashboard. An app can override any of them by defining a view with
+// the same name.
+//
+//go:embed builtin-views
+var builtinViewsFS embed.FS
+
 type templKind int
 
 const (
@@ -142,41 +150,56 @@ func (app *App) loadTemplates() (*template.Template, error) {
 	root.Funcs(funcs)
 
 	var templs []*templDef
+	templsByName := make(map[string]*templDef)
 
-	err := fs.WalkDir(app.viewsFS, ".", func(fullPath string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return fmt.Errorf("%s: %w", fullPath, err)
-		}
-		relPath := fullPath //strings.TrimPrefix(fullPath, app.Configuration.ViewsSubdir+"/")
-		// log.Printf("loadTemplates sees: %v", relPath)
-		if !strings.HasSuffix(relPath, templateSuffix) {
-			return nil
-		}
-		name := strings.TrimSuffix(relPath, templateSuffix)
-		baseName := strings.TrimSuffix(d.Name(), templateSuffix)
-		code := string(must(fs.ReadFile(app.viewsFS, fullPath)))
-
-		var kind templKind
-		if strings.HasPrefix(relPath, "layouts/") {
-			kind = layoutTempl
-		} else if strings.HasPrefix(baseName, "c-") {
-			kind = componentTempl
-			name = baseName
-		} else if strings.Contains(baseName, "__") {
-			kind = partialTempl
-		} else {
-			kind = pageTempl
-		}
+	collect := func(fsys fs.FS) error {
+		return fs.WalkDir(fsys, ".", func(fullPath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return fmt.Errorf("%s: %w", fullPath, err)
+			}
+			relPath := fullPath //strings.TrimPrefix(fullPath, app.Configuration.ViewsSubdir+"/")
+			// log.Printf("loadTemplates sees: %v", relPath)
+			if !strings.HasSuffix(relPath, templateSuffix) {
+				return nil
+			}
+			name := strings.TrimSuffix(relPath, templateSuffix)
+			baseName := strings.TrimSuffix(d.Name(), templateSuffix)
+			code := string(must(fs.ReadFile(fsys, fullPath)))
+
+			var kind templKind
+			if strings.HasPrefix(relPath, "layouts/") {
+				kind = layoutTempl
+			} else if strings.HasPrefix(baseName, "c-") {
+				kind = componentTempl
+				name = baseName
+			} else if strings.Contains(baseName, "__") {
+				kind = partialTempl
+			} else {
+				kind = pageTempl
+			}
 
-		templs = append(templs, &templDef{
-			name: name,
-			path: fullPath,
-			code: code,
-			kind: kind,
-			tmpl: root.New(name),
+			if prev := templsByName[name]; prev != nil {
+				// app views override built-in ones
+				prev.path, prev.code, prev.kind = fullPath, code, kind
+				return nil
+			}
+			tmpl := &templDef{
+				name: name,
+				path: fullPath,
+				code: code,
+				kind: kind,
+				tmpl: root.New(name),
+			}
+			templs = append(templs, tmpl)
+			templsByName[name] = tmpl
+			return nil
 		})
-		return nil
-	})
+	}
+	err := collect(must(fs.Sub(builtinViewsFS, "builtin-views")))
+	if err != nil {
+		return nil, err
+	}
+	err = collect(app.viewsFS)
 	if err != nil {
 		return nil, err
 	}
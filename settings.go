@@ -36,8 +36,10 @@ type Settings struct {
 
 	// job options
 	WorkerCount           int
+	WorkerSets            map[string]int // worker counts for job sets other than the default one
 	EphemeralWorkerCount  int
 	EphemeralQueueMaxSize int
+	EphemeralDrainTimeout jsonext.Duration // how long to keep running queued ephemeral jobs on shutdown, 10s by default
 
 	// app options
 	AppName                  string // user-visible app name
@@ -0,0 +1,39 @@
+package mvp
+
+import (
+	"github.com/andreyvit/mvp/mvpopenapi"
+	"github.com/andreyvit/mvp/mvprpc"
+	"github.com/andreyvit/mvp/mvpts"
+)
+
+// TypeScriptClient generates a TypeScript client for the implemented methods
+// of the given APIs, or for all methods implemented via MethodImpl (except for
+// job methods) if no APIs are given. Besides a Client class with a method per
+// RPC method, it declares the types of method params and results, APIError
+// for the shape produced by BuildAPIErrorResponse, and ErrorID, a union of
+// the IDs of errors created via httperrors.Define that stays open to other
+// strings, as httperrors.Errorf can produce any ID.
+func (app *App) TypeScriptClient(apis ...*mvprpc.API) []byte {
+	g := mvpopenapi.NewGenerator()
+	apiErrorSchema(g, "APIError")
+	var ids []any
+	for _, id := range definedAPIErrorIDs() {
+		ids = append(ids, id)
+	}
+	g.Schemas["ErrorID"] = &mvpopenapi.Schema{
+		Description: "Error ID, one of the predefined ones or any other string",
+		AnyOf:       []*mvpopenapi.Schema{{Type: "string", Enum: ids}, {Type: "string"}},
+	}
+	g.Schemas["APIError"].Properties["error"] = &mvpopenapi.Schema{Ref: "#/components/schemas/ErrorID"}
+
+	c := &mvpts.Client{}
+	for _, m := range app.rpcMethodImpls(apis...) {
+		tm := &mvpts.Method{Name: m.Name, Params: g.Schema(m.InType)}
+		if m.OutType != nil {
+			tm.Result = resultSchema(g, m.OutType)
+		}
+		c.Methods = append(c.Methods, tm)
+	}
+	c.Schemas = g.Schemas
+	return c.Generate()
+}
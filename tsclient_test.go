@@ -0,0 +1,51 @@
+package mvp_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andreyvit/mvp"
+	"github.com/andreyvit/mvp/mvprpc"
+)
+
+type testUser struct {
+	ID      string    `json:"id"`
+	Manager *testUser `json:"manager"`
+}
+
+type testGetUserIn struct {
+	ID string `json:"id"`
+}
+
+func TestTypeScriptClient(t *testing.T) {
+	h := newTestApp(t, nil)
+	api := &mvprpc.API{Name: "test"}
+	getUser := api.Method("users.get", &testGetUserIn{}, &testUser{})
+	h.App.MethodImpl(getUser, func(rc *mvp.RC, in *testGetUserIn) (*testUser, error) {
+		return &testUser{ID: in.ID}, nil
+	})
+	other := &mvprpc.API{Name: "other"}
+	h.App.MethodImpl(other.Method("other.ping", nil, nil), func(rc *mvp.RC) error {
+		return nil
+	})
+
+	a := string(h.App.TypeScriptClient(api))
+	for _, e := range []string{
+		"export type ErrorID =\n",
+		"  | \"too_many_requests\"\n",
+		"  | \"not_acceptable\"\n",
+		"  | (string & {});\n",
+		"export interface APIError {\n",
+		"  error: ErrorID;\n",
+		"export interface testUser {\n  id: string;\n  manager: testUser | null;\n}\n",
+		"  usersGet(params: testGetUserIn): Promise<testUser> {\n" +
+			"    return this.transport(\"users.get\", params) as Promise<testUser>;\n",
+	} {
+		if !strings.Contains(a, e) {
+			t.Errorf("** missing:\n%s\nin:\n%s", e, a)
+		}
+	}
+	if strings.Contains(a, "ping") {
+		t.Errorf("** includes methods of other APIs:\n%s", a)
+	}
+}